@@ -4,11 +4,14 @@ import (
 	_ "final_project/docs"
 	"final_project/initializers"
 	"final_project/internal/api"
+	"final_project/internal/utils"
+	"time"
 )
 
 func init() {
 	initializers.GetKeys()
 	initializers.DBConnector()
+	initializers.StorageConnector()
 }
 
 // @title Canteen SDU
@@ -24,6 +27,8 @@ func init() {
 
 func main() {
 
+	go utils.CleanUpIdempotencyKeys(initializers.DB, time.Hour)
+
 	router := api.SetupRouter()
 	router.Run(":8080")
 
@@ -0,0 +1,69 @@
+package initializers
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// deleteRules maps ON DELETE actions to pg_constraint.confdeltype.
+var deleteRules = map[string]string{
+	"NO ACTION":   "a",
+	"RESTRICT":    "r",
+	"CASCADE":     "c",
+	"SET NULL":    "n",
+	"SET DEFAULT": "d",
+}
+
+// syncDeleteRules recreates the foreign keys whose ON DELETE action differs
+// from the constraint tag of the models. AutoMigrate only creates missing
+// foreign keys and leaves existing ones as they are.
+func syncDeleteRules(db *gorm.DB, models ...interface{}) error {
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			relations := []*schema.Relationship{rel}
+			if rel.JoinTable != nil {
+				relations = nil
+				for _, joinRel := range rel.JoinTable.Relationships.Relations {
+					relations = append(relations, joinRel)
+				}
+			}
+			for _, relation := range relations {
+				constraint := relation.ParseConstraint()
+				if constraint == nil || constraint.OnDelete == "" {
+					continue
+				}
+				if err := syncDeleteRule(db, constraint); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func syncDeleteRule(db *gorm.DB, constraint *schema.Constraint) error {
+	var current string
+	err := db.Raw("SELECT confdeltype::text FROM pg_constraint WHERE conname = ? AND conrelid = to_regclass(?)",
+		constraint.Name, constraint.Schema.Table).Scan(&current).Error
+	if err != nil {
+		return err
+	}
+	if current == "" || current == deleteRules[strings.ToUpper(constraint.OnDelete)] {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		table := clause.Table{Name: constraint.Schema.Table}
+		if err := tx.Exec("ALTER TABLE ? DROP CONSTRAINT ?", table, clause.Column{Name: constraint.Name}).Error; err != nil {
+			return err
+		}
+		sql, vars := constraint.Build()
+		return tx.Exec("ALTER TABLE ? ADD "+sql, append([]interface{}{table}, vars...)...).Error
+	})
+}
@@ -19,9 +19,86 @@ func DBConnector() {
 		panic("Failed to connect to DB")
 	}
 
-	DB.AutoMigrate(models.User{}, models.Order{}, models.Basket{}, models.BasketItem{}, models.Menu{}, models.OrderDetail{})
-	if err != nil {
+	if err := Migrate(DB); err != nil {
 		panic(err)
 	}
+}
+
+// schemaModels are the models with a table, in the order they are migrated.
+var schemaModels = []interface{}{
+	models.Tag{},
+	models.User{},
+	models.Category{},
+	models.Order{},
+	models.Basket{},
+	models.BasketItem{},
+	models.Menu{},
+	models.OrderDetail{},
+	models.Session{},
+	models.OrderStatusHistory{},
+	models.MenuSchedule{},
+	models.ModifierGroup{},
+	models.ModifierOption{},
+	models.OrderDetailModifier{},
+	models.Combo{},
+	models.ComboSlot{},
+	models.OrderCombo{},
+	models.OrderComboChoice{},
+	models.BasketCombo{},
+	models.BasketComboChoice{},
+	models.MenuPriceHistory{},
+	models.MenuTranslation{},
+	models.CategoryTranslation{},
+	models.Review{},
+	models.PickupSlot{},
+	models.PickupCounter{},
+	models.IdempotencyKey{},
+}
+
+// Migrate brings the schema up to date, fills in columns added later and
+// seeds the default tags.
+func Migrate(db *gorm.DB) error {
+	err := db.AutoMigrate(schemaModels...)
+	if err != nil {
+		return err
+	}
+	err = syncDeleteRules(db, schemaModels...)
+	if err != nil {
+		return err
+	}
+
+	// Lines get their unit price from what was charged, as older ones have
+	// none or one without the modifiers.
+	err = db.Exec(`UPDATE order_details SET unit_price = total_cost / quantity
+		WHERE quantity > 0 AND unit_price IS DISTINCT FROM total_cost / quantity`).Error
+	if err != nil {
+		return err
+	}
+	err = db.Exec("UPDATE basket_items SET unit_price = menus.price FROM menus WHERE menus.id = basket_items.item_id AND basket_items.unit_price IS NULL").Error
+	if err != nil {
+		return err
+	}
+
+	// Orders placed before pickup numbers get them in the order they were placed.
+	err = db.Exec(`UPDATE orders SET pickup_date = numbered.day, pickup_number = numbered.number
+		FROM (SELECT id, DATE(created_at) AS day, ROW_NUMBER() OVER (PARTITION BY DATE(created_at) ORDER BY created_at, id) AS number
+			FROM orders) AS numbered
+		WHERE numbered.id = orders.id AND orders.pickup_date IS NULL`).Error
+	if err != nil {
+		return err
+	}
+	// Counters carry on from the numbers orders already have.
+	err = db.Exec(`INSERT INTO pickup_counters (day, number)
+		SELECT pickup_date, MAX(pickup_number) FROM orders WHERE pickup_date IS NOT NULL GROUP BY pickup_date
+		ON CONFLICT (day) DO UPDATE SET number = GREATEST(pickup_counters.number, EXCLUDED.number)`).Error
+	if err != nil {
+		return err
+	}
 
+	for _, tag := range models.DefaultTags {
+		if err := db.Where("name = ?", tag.Name).FirstOrCreate(&tag).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,16 @@
+package initializers
+
+import (
+	"final_project/internal/storage"
+	"os"
+)
+
+var Images storage.Storage
+
+func StorageConnector() {
+	dir := os.Getenv("image_dir")
+	if dir == "" {
+		dir = "uploads/images"
+	}
+	Images = storage.NewLocalStorage(dir, "/images")
+}
@@ -0,0 +1,305 @@
+package admin
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strconv"
+)
+
+// ListUsers godoc
+// @Summary List users
+// @Description Lists users, optionally filtered by a username/email search, role or disabled flag. Admin only.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param search query string false "Substring of username or email"
+// @Param role query string false "admin or client"
+// @Param disabled query bool false "Filter by disabled flag"
+// @Success 200 {array} map[string]interface{} "List of users"
+// @Failure 400 {object} map[string]interface{} "error: Invalid role or Invalid disabled filter"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve users"
+// @Router /admin/users [get]
+func ListUsers(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users", utils.AuthMiddleware())
+	{
+		adminRoutes.GET("/", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			query := initializers.DB.Model(&models.User{})
+			if search := c.Query("search"); search != "" {
+				pattern := "%" + search + "%"
+				query = query.Where("username ILIKE ? OR email ILIKE ?", pattern, pattern)
+			}
+			if roleFilter := c.Query("role"); roleFilter != "" {
+				if !validRole(models.Role(roleFilter)) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+					return
+				}
+				query = query.Where("role = ?", roleFilter)
+			}
+			if disabledFilter := c.Query("disabled"); disabledFilter != "" {
+				disabled, err := strconv.ParseBool(disabledFilter)
+				if err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid disabled filter"})
+					return
+				}
+				query = query.Where("disabled = ?", disabled)
+			}
+
+			var users []models.User
+			if err := query.Order("id").Find(&users).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+				return
+			}
+
+			response := make([]map[string]interface{}, 0, len(users))
+			for _, user := range users {
+				response = append(response, userResponse(user))
+			}
+			c.JSON(http.StatusOK, response)
+		})
+	}
+}
+
+// GetUser godoc
+// @Summary Get a user
+// @Description Returns a single user. Admin only.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{} "User"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Router /admin/users/{userId} [get]
+func GetUser(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users", utils.AuthMiddleware())
+	{
+		adminRoutes.GET("/:userId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var user models.User
+			if err := initializers.DB.First(&user, c.Param("userId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+				return
+			}
+			c.JSON(http.StatusOK, userResponse(user))
+		})
+	}
+}
+
+// UpdateUserRole godoc
+// @Summary Promote or demote a user
+// @Description Changes the role of a user. Admins cannot change their own role. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param userId path string true "User ID"
+// @Param role body UpdateRoleData true "New role"
+// @Success 200 {object} map[string]interface{} "message: User role updated successfully"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Invalid role or You cannot change your own role"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update user role"
+// @Router /admin/users/{userId}/role [patch]
+func UpdateUserRole(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users", utils.AuthMiddleware())
+	{
+		adminRoutes.PATCH("/:userId/role", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var updateData UpdateRoleData
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			newRole := models.Role(updateData.Role)
+			if !validRole(newRole) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid role"})
+				return
+			}
+
+			user, ok := findOtherUser(c)
+			if !ok {
+				return
+			}
+
+			user.Role = newRole
+			if err := initializers.DB.Model(&user).Update("role", newRole).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully", "user": userResponse(user)})
+		})
+	}
+}
+
+// UpdateUserStatus godoc
+// @Summary Disable or enable a user
+// @Description Disables or re-enables a user account. Disabling revokes all of the user's sessions. Admin only.
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param userId path string true "User ID"
+// @Param status body UpdateStatusData true "Disabled flag"
+// @Success 200 {object} map[string]interface{} "message: User status updated successfully"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or You cannot change your own account"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update user status"
+// @Router /admin/users/{userId}/status [patch]
+func UpdateUserStatus(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users", utils.AuthMiddleware())
+	{
+		adminRoutes.PATCH("/:userId/status", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var updateData UpdateStatusData
+			if err := c.BindJSON(&updateData); err != nil || updateData.Disabled == nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			user, ok := findOtherUser(c)
+			if !ok {
+				return
+			}
+
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Model(&user).Update("disabled", *updateData.Disabled).Error; err != nil {
+					return err
+				}
+				if *updateData.Disabled {
+					return utils.RevokeUserSessions(tx, user.ID)
+				}
+				return nil
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user status"})
+				return
+			}
+			user.Disabled = *updateData.Disabled
+			c.JSON(http.StatusOK, gin.H{"message": "User status updated successfully", "user": userResponse(user)})
+		})
+	}
+}
+
+// DeleteUser godoc
+// @Summary Delete a user
+// @Description Deletes a user together with their sessions, basket and reviews. Users with orders cannot be deleted and should be disabled instead. Admin only.
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param userId path string true "User ID"
+// @Success 200 {object} map[string]interface{} "message: User deleted successfully"
+// @Failure 400 {object} map[string]interface{} "error: You cannot change your own account"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: User not found"
+// @Failure 409 {object} map[string]interface{} "error: User has orders, disable the account instead"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete user"
+// @Router /admin/users/{userId} [delete]
+func DeleteUser(router *gin.Engine) {
+	adminRoutes := router.Group("/admin/users", utils.AuthMiddleware())
+	{
+		adminRoutes.DELETE("/:userId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			user, ok := findOtherUser(c)
+			if !ok {
+				return
+			}
+
+			var orderCount int64
+			if err := initializers.DB.Model(&models.Order{}).Where("user_id = ?", user.ID).Count(&orderCount).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+				return
+			}
+			if orderCount > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "User has orders, disable the account instead"})
+				return
+			}
+
+			// Sessions, baskets, allergen and diet choices, reviews and
+			// idempotency keys are deleted with the user by their foreign
+			// keys. Ratings are averaged from the reviews when read, so nothing
+			// else changes. Status and price changes the user made stay in the
+			// histories without who made them.
+			if err := initializers.DB.Delete(&user).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+		})
+	}
+}
+
+// findOtherUser loads the user from the :userId param and refuses to let an
+// admin act on their own account. It writes the error response itself.
+func findOtherUser(c *gin.Context) (models.User, bool) {
+	var user models.User
+	if err := initializers.DB.First(&user, c.Param("userId")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		}
+		return user, false
+	}
+
+	currentID, _ := c.Get("ID")
+	if user.ID == currentID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own account"})
+		return user, false
+	}
+	return user, true
+}
+
+func validRole(role models.Role) bool {
+	return role == models.Admin || role == models.Client
+}
+
+func userResponse(user models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":       user.ID,
+		"username": user.Username,
+		"email":    user.Email,
+		"role":     user.Role,
+		"disabled": user.Disabled,
+	}
+}
+
+type UpdateRoleData struct {
+	Role string `json:"role" binding:"required"`
+}
+
+type UpdateStatusData struct {
+	Disabled *bool `json:"disabled"`
+}
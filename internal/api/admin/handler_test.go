@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"final_project/internal/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func mustCreate(t *testing.T, db *gorm.DB, value interface{}) {
+	t.Helper()
+	if err := db.Create(value).Error; err != nil {
+		t.Fatalf("create %T: %v", value, err)
+	}
+}
+
+func deleteUser(t *testing.T, db *gorm.DB, userID uint) *httptest.ResponseRecorder {
+	t.Helper()
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: models.Admin}
+	mustCreate(t, db, &admin)
+	tokens, err := utils.CreateSession(db, admin)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	DeleteUser(router)
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/admin/users/%d", userID), nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestDeleteUserReferencedEverywhere gives the user a row in every table that
+// refers to users, except orders, and checks that deleting them succeeds.
+func TestDeleteUserReferencedEverywhere(t *testing.T) {
+	db := testdb.Open(t, "api_admin")
+	gin.SetMode(gin.TestMode)
+
+	user := models.User{Username: "leaving", Email: "leaving@example.com", Role: models.Client}
+	mustCreate(t, db, &user)
+	other := models.User{Username: "staying", Email: "staying@example.com", Role: models.Client}
+	mustCreate(t, db, &other)
+
+	allergen := models.Tag{Name: "test-nuts", Kind: models.Allergen}
+	mustCreate(t, db, &allergen)
+	diet := models.Tag{Name: "test-vegan", Kind: models.Dietary}
+	mustCreate(t, db, &diet)
+	if err := db.Model(&user).Association("Allergens").Append(&allergen); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&user).Association("Diets").Append(&diet); err != nil {
+		t.Fatal(err)
+	}
+
+	item := models.Menu{Name: "Lagman", Price: decimal.NewFromInt(1800), Quantity: 10, IsAvailable: true}
+	mustCreate(t, db, &item)
+	group := models.ModifierGroup{
+		ItemID:    item.ID,
+		Name:      "Extras",
+		Kind:      models.Extra,
+		MaxSelect: 1,
+		Options:   []models.ModifierOption{{Name: "Egg", PriceDelta: decimal.NewFromInt(150)}},
+	}
+	mustCreate(t, db, &group)
+	combo := models.Combo{Name: "Lunch", Price: decimal.NewFromInt(2500), IsAvailable: true, Slots: []models.ComboSlot{{Name: "Main", Items: []models.Menu{item}}}}
+	mustCreate(t, db, &combo)
+
+	basket := models.Basket{UserID: user.ID}
+	mustCreate(t, db, &basket)
+	mustCreate(t, db, &models.BasketItem{BasketID: basket.ID, ItemID: item.ID, Quantity: 1, Options: group.Options})
+	mustCreate(t, db, &models.BasketCombo{
+		BasketID: basket.ID,
+		ComboID:  combo.ID,
+		Quantity: 1,
+		Choices:  []models.BasketComboChoice{{SlotID: combo.Slots[0].ID, ItemID: item.ID}},
+	})
+	if _, err := utils.CreateSession(db, user); err != nil {
+		t.Fatal(err)
+	}
+	mustCreate(t, db, &models.IdempotencyKey{UserID: user.ID, Key: "retry-1", StatusCode: http.StatusCreated})
+	mustCreate(t, db, &models.Review{ItemID: item.ID, UserID: user.ID, Rating: 4})
+	mustCreate(t, db, &models.MenuPriceHistory{ItemID: item.ID, OldPrice: decimal.NewFromInt(1700), NewPrice: item.Price, ChangedByID: &user.ID})
+
+	otherOrder := models.Order{UserID: other.ID, OrderStatus: models.Preparing}
+	mustCreate(t, db, &otherOrder)
+	mustCreate(t, db, &models.OrderStatusHistory{OrderID: otherOrder.ID, FromStatus: models.Preparing, ToStatus: models.Ready, ChangedByID: &user.ID})
+
+	w := deleteUser(t, db, user.ID)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	var count int64
+	if err := db.Model(&models.User{}).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Error("user was not deleted")
+	}
+	for _, table := range []string{"user_allergens", "user_diets", "sessions", "baskets", "idempotency_keys", "reviews"} {
+		if err := db.Table(table).Where("user_id = ?", user.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s rows left = %d, want 0", table, count)
+		}
+	}
+	for _, table := range []string{"basket_items", "basket_item_options", "basket_combos", "basket_combo_choices"} {
+		if err := db.Table(table).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s rows left = %d, want 0", table, count)
+		}
+	}
+	for _, table := range []string{"order_status_histories", "menu_price_histories"} {
+		if err := db.Table(table).Where("changed_by_id = ?", user.ID).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%s still refer to the user", table)
+		}
+	}
+	if err := db.Model(&models.OrderStatusHistory{}).Where("order_id = ?", otherOrder.ID).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("history of the other user's order = %d rows, want 2", count)
+	}
+}
+
+func TestDeleteUserWithOrders(t *testing.T) {
+	db := testdb.Open(t, "api_admin")
+	gin.SetMode(gin.TestMode)
+
+	user := models.User{Username: "customer", Email: "customer@example.com", Role: models.Client}
+	mustCreate(t, db, &user)
+	mustCreate(t, db, &models.Order{UserID: user.ID, OrderStatus: models.Preparing})
+
+	w := deleteUser(t, db, user.ID)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusConflict)
+	}
+}
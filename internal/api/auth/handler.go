@@ -1,10 +1,10 @@
 package auth
 
 import (
+	"errors"
 	"final_project/initializers"
 	"final_project/internal/models"
 	"final_project/internal/utils"
-	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"net/http"
@@ -19,6 +19,8 @@ import (
 // @Param   user     body      models.User  true  "Login Credentials"
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} map[string]interface{}
+// @Failure 401 {object} map[string]interface{}
+// @Failure 403 {object} map[string]interface{} "error: Account is disabled"
 // @Failure 500 {object} map[string]interface{}
 // @Router /login [post]
 func Login(router *gin.Engine) {
@@ -29,29 +31,112 @@ func Login(router *gin.Engine) {
 			return
 		}
 		var existingUser models.User
-		result := initializers.DB.Select("ID", "username", "password", "role").Where("username = ?", loginUser.Username).First(&existingUser)
+		result := initializers.DB.Select("ID", "username", "password", "role", "disabled").Where("username = ?", loginUser.Username).First(&existingUser)
 		if result.Error != nil || !utils.CheckPassword(existingUser.Password, loginUser.Password) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid username or password"})
 			return
 		}
+		if existingUser.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			return
+		}
 
-		token, err := utils.GenerateToken(existingUser.Username, string(existingUser.Role), int(existingUser.ID))
+		tokens, err := utils.CreateSession(initializers.DB, existingUser)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
 		}
-		fmt.Println("Generated token:", token)
-		c.JSON(http.StatusOK, gin.H{"token": token})
+		c.JSON(http.StatusOK, gin.H{
+			"token":         tokens.AccessToken,
+			"access_token":  tokens.AccessToken,
+			"refresh_token": tokens.RefreshToken,
+			"expires_in":    tokens.ExpiresIn,
+		})
+	})
+}
+
+// RefreshToken godoc
+// @Summary Refresh access token
+// @Description Exchanges a refresh token for a new access and refresh token pair. The used refresh token becomes invalid; presenting it again revokes the session.
+// @Tags auth
+// @Accept  json
+// @Produce  json
+// @Param   input     body      RefreshRequest  true  "Refresh token"
+// @Success 200 {object} utils.TokenPair
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 401 {object} map[string]interface{} "error: Invalid refresh token or Session has been revoked"
+// @Failure 500 {object} map[string]interface{} "error: Failed to refresh token"
+// @Router /token/refresh [post]
+func RefreshToken(router *gin.Engine) {
+	router.POST("/token/refresh", func(c *gin.Context) {
+		var req RefreshRequest
+		if err := c.BindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+
+		tokens, err := utils.RotateSession(initializers.DB, req.RefreshToken)
+		if err != nil {
+			if errors.Is(err, utils.ErrInvalidRefreshToken) || errors.Is(err, utils.ErrSessionRevoked) || errors.Is(err, utils.ErrRefreshTokenReused) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+			return
+		}
+		c.JSON(http.StatusOK, tokens)
+	})
+}
+
+// Logout godoc
+// @Summary Logout
+// @Description Revokes the current session; its access and refresh tokens stop working.
+// @Tags auth
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "message: Logged out successfully"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 500 {object} map[string]interface{} "error: Failed to logout"
+// @Router /logout [post]
+func Logout(router *gin.Engine) {
+	router.POST("/logout", utils.AuthMiddleware(), func(c *gin.Context) {
+		sessionID, _ := c.Get("sid")
+		if err := utils.RevokeSession(initializers.DB, sessionID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	})
+}
+
+// StreamTicket godoc
+// @Summary Get a stream ticket
+// @Description Issues a ticket for clients that cannot set headers on an event stream, such as a browser EventSource. Pass it in the ticket query parameter of /orders/stream or /kitchen/stream. A ticket opens one stream and expires after 30 seconds.
+// @Tags auth
+// @Produce  json
+// @Security ApiKeyAuth
+// @Success 201 {object} map[string]interface{} "ticket, expires_in"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized"
+// @Failure 500 {object} map[string]interface{} "error: Failed to issue stream ticket"
+// @Router /stream/ticket [post]
+func StreamTicket(router *gin.Engine) {
+	router.POST("/stream/ticket", utils.AuthMiddleware(), func(c *gin.Context) {
+		ticket, err := utils.IssueStreamTicket(c.GetHeader("Authorization"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue stream ticket"})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"ticket": ticket, "expires_in": int(utils.StreamTicketTTL.Seconds())})
 	})
 }
 
 // SignUp godoc
 // @Summary SignUp
-// @Description register a new user
+// @Description register a new user; new accounts always get the client role
 // @ID create-account
 // @Accept  json
 // @Produce  json
-// @Param   input     body      models.User  true  "User Registration Data"
+// @Param   input     body      SignUpRequest  true  "User Registration Data"
 // @Success 201 {object} map[string]interface{} "message: User signed up successfully"
 // @Failure 400 {object} map[string]interface{} "error: Invalid request or Invalid email format"
 // @Failure 500 {object} map[string]interface{} "error: Failed to sign up user"
@@ -59,18 +144,25 @@ func Login(router *gin.Engine) {
 func SignUp(router *gin.Engine) {
 
 	router.POST("/signup", func(c *gin.Context) {
-		var newUser models.User
-		if err := c.BindJSON(&newUser); err != nil {
+		var signUpReq SignUpRequest
+		if err := c.BindJSON(&signUpReq); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 			return
 		}
 		validate := validator.New()
 
-		if err := validate.Struct(newUser); err != nil {
+		if err := validate.Struct(signUpReq); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email format"})
 			return
 		}
 
+		newUser := models.User{
+			Username: signUpReq.Username,
+			Email:    signUpReq.Email,
+			Password: signUpReq.Password,
+			Role:     models.Client,
+		}
+
 		if err := utils.SignupUser(initializers.DB, newUser); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign up user"})
 			return
@@ -79,3 +171,13 @@ func SignUp(router *gin.Engine) {
 		c.JSON(http.StatusCreated, gin.H{"message": "User signed up successfully"})
 	})
 }
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type SignUpRequest struct {
+	Username string `json:"username"`
+	Email    string `json:"email" validate:"email"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,144 @@
+package basket
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+)
+
+// UpdateBasketCombo godoc
+// @Summary Change quantity of a basket combo
+// @Description Sets the quantity of one combo line in the user's basket.
+// @Tags basket
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param lineId path string true "Basket combo line ID"
+// @Param quantity body UpdateBasketItemData true "New quantity"
+// @Success 200 {object} map[string]interface{} "message: Basket combo updated successfully, total_price"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Quantity must be positive"
+// @Failure 404 {object} map[string]interface{} "error: Combo not found in basket"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update basket combo"
+// @Router /basket/combos/{lineId} [patch]
+func UpdateBasketCombo(router *gin.Engine) {
+	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
+	{
+		basketRoutes.PATCH("/combos/:lineId", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			var updateData UpdateBasketItemData
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			if updateData.Quantity <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
+				return
+			}
+
+			tx := initializers.DB.Begin()
+
+			basket, basketCombo, err := findBasketCombo(tx, userID.(uint), c.Param("lineId"))
+			if err != nil {
+				tx.Rollback()
+				basketComboErrorResponse(c, err)
+				return
+			}
+
+			if err := tx.Model(&basketCombo).Update("quantity", updateData.Quantity).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket combo"})
+				return
+			}
+			if err := recalculateBasket(tx, &basket); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket total"})
+				return
+			}
+
+			tx.Commit()
+			c.JSON(http.StatusOK, gin.H{"message": "Basket combo updated successfully", "total_price": basket.TotalPrice.String()})
+		})
+	}
+}
+
+// RemoveBasketCombo godoc
+// @Summary Remove a combo from the basket
+// @Description Removes one combo line from the user's basket.
+// @Tags basket
+// @Produce json
+// @Security ApiKeyAuth
+// @Param lineId path string true "Basket combo line ID"
+// @Success 200 {object} map[string]interface{} "message: Combo removed from basket, total_price"
+// @Failure 404 {object} map[string]interface{} "error: Combo not found in basket"
+// @Failure 500 {object} map[string]interface{} "error: Failed to remove combo from basket"
+// @Router /basket/combos/{lineId} [delete]
+func RemoveBasketCombo(router *gin.Engine) {
+	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
+	{
+		basketRoutes.DELETE("/combos/:lineId", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			tx := initializers.DB.Begin()
+
+			basket, basketCombo, err := findBasketCombo(tx, userID.(uint), c.Param("lineId"))
+			if err != nil {
+				tx.Rollback()
+				basketComboErrorResponse(c, err)
+				return
+			}
+
+			if err := tx.Delete(&basketCombo).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove combo from basket"})
+				return
+			}
+			if err := recalculateBasket(tx, &basket); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket total"})
+				return
+			}
+
+			tx.Commit()
+			c.JSON(http.StatusOK, gin.H{"message": "Combo removed from basket", "total_price": basket.TotalPrice.String()})
+		})
+	}
+}
+
+// findBasketCombo locks the user's basket and returns it with the given combo line.
+func findBasketCombo(tx *gorm.DB, userID uint, lineID string) (models.Basket, models.BasketCombo, error) {
+	var basket models.Basket
+	var basketCombo models.BasketCombo
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&basket).Error; err != nil {
+		return basket, basketCombo, err
+	}
+	err := tx.Where("basket_id = ? AND id = ?", basket.ID, lineID).First(&basketCombo).Error
+	return basket, basketCombo, err
+}
+
+func basketComboErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Combo not found in basket"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+}
+
+// comboErrorResponse reports why a combo with its choices cannot be added.
+func comboErrorResponse(c *gin.Context, comboID uint, err error) {
+	switch {
+	case errors.Is(err, utils.ErrComboNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Combo not found", "combo_id": comboID})
+	case errors.Is(err, utils.ErrComboUnavailable):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Combo is not available", "combo_id": comboID})
+	case errors.Is(err, utils.ErrInvalidComboChoices):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid combo choices", "details": err.Error(), "combo_id": comboID})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add combo to basket"})
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
 	"net/http"
 )
 
@@ -46,7 +48,7 @@ func DeleteFromBasket(router *gin.Engine) {
 				return
 			}
 
-			if err := tx.Where("basket_id = ?", basket.ID).Delete(&models.BasketItem{}).Error; err != nil {
+			if err := deleteBasketItems(tx, basket.ID); err != nil {
 				tx.Rollback()
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete basket items", "details": err.Error()})
 				return
@@ -58,6 +60,7 @@ func DeleteFromBasket(router *gin.Engine) {
 				return
 			}
 
+			tx.Commit()
 			c.JSON(http.StatusOK, gin.H{"message": "Basket deleted successfully"})
 		})
 	}
@@ -65,20 +68,23 @@ func DeleteFromBasket(router *gin.Engine) {
 
 // AddToBasket godoc
 // @Summary Add items to basket
-// @Description Adds one or more items to the user's basket.
+// @Description Adds one or more items with their chosen modifier options, and combos with their chosen items, to the user's basket. Adding an item with the same options, or a combo with the same choices, as a basket line increases that line's quantity. Requests with an Idempotency-Key header are only handled once.
 // @Tags basket
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Param items body struct { Items []struct { ItemID uint "json:\"item_id\""; Quantity int "json:\"quantity\"" } "json:\"items\"" } true "Items to add"
+// @Param items body struct { Items []struct { ItemID uint "json:\"item_id\""; Quantity int "json:\"quantity\""; Options []uint "json:\"options\"" } "json:\"items\""; Combos []struct { ComboID uint "json:\"combo_id\""; Quantity int "json:\"quantity\""; Choices []utils.ComboChoice "json:\"choices\"" } "json:\"combos\"" } true "Items and combos to add"
+// @Param Idempotency-Key header string false "Unique key of the request; retries with the same key get the first response"
 // @Success 200 {object} map[string]interface{} "message: Items added to basket successfully, basketId"
-// @Failure 400 {object} map[string]interface{} "error: User ID not found or Invalid JSON body"
+// @Failure 400 {object} map[string]interface{} "error: User ID not found or Invalid JSON body or Quantity must be positive or Product not found or Invalid modifiers or Combo not found or Combo is not available or Invalid combo choices"
+// @Failure 409 {object} map[string]interface{} "error: A request with this idempotency key is still being processed"
+// @Failure 422 {object} map[string]interface{} "error: Idempotency key was already used for a different request"
 // @Failure 500 {object} map[string]interface{} "error: Failed to retrieve or create basket or add item to basket"
 // @Router /basket [post]
 func AddToBasket(router *gin.Engine) {
 	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
 	{
-		basketRoutes.POST("/", func(c *gin.Context) {
+		basketRoutes.POST("/", utils.IdempotencyMiddleware(), func(c *gin.Context) {
 			userID, exists := c.Get("ID")
 			if !exists {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "User ID not found"})
@@ -87,9 +93,15 @@ func AddToBasket(router *gin.Engine) {
 
 			var basketAddRequest struct {
 				Items []struct {
-					ItemID   uint `json:"item_id"`
-					Quantity int  `json:"quantity"`
+					ItemID   uint   `json:"item_id"`
+					Quantity int    `json:"quantity"`
+					Options  []uint `json:"options"`
 				} `json:"items"`
+				Combos []struct {
+					ComboID  uint                `json:"combo_id"`
+					Quantity int                 `json:"quantity"`
+					Choices  []utils.ComboChoice `json:"choices"`
+				} `json:"combos"`
 			}
 
 			if err := c.BindJSON(&basketAddRequest); err != nil {
@@ -105,34 +117,137 @@ func AddToBasket(router *gin.Engine) {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve or create basket"})
 				return
 			}
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&basket, basket.ID).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve or create basket"})
+				return
+			}
 
 			for _, item := range basketAddRequest.Items {
-				basketItem := models.BasketItem{
-					BasketID: basket.ID,
-					ItemID:   item.ItemID,
-					Quantity: item.Quantity,
+				if item.Quantity <= 0 {
+					tx.Rollback()
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive", "item_id": item.ItemID})
+					return
+				}
+				var menuItem models.Menu
+				if err := tx.First(&menuItem, item.ItemID).Error; err != nil {
+					tx.Rollback()
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Product not found", "item_id": item.ItemID})
+					return
+				}
+
+				options, _, err := utils.ResolveOptions(tx, item.ItemID, item.Options)
+				if err != nil {
+					tx.Rollback()
+					if errors.Is(err, utils.ErrInvalidModifiers) {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid modifiers", "details": err.Error(), "item_id": item.ItemID})
+						return
+					}
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to basket"})
+					return
+				}
+				optionsKey := utils.OptionsKey(options)
+
+				var basketItem models.BasketItem
+				err = tx.Where("basket_id = ? AND item_id = ? AND options_key = ?", basket.ID, item.ItemID, optionsKey).First(&basketItem).Error
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					tx.Rollback()
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to basket"})
+					return
+				}
+				if err == nil {
+					err = tx.Model(&basketItem).Update("quantity", basketItem.Quantity+item.Quantity).Error
+				} else {
+					basketItem = models.BasketItem{
+						BasketID:   basket.ID,
+						ItemID:     item.ItemID,
+						Quantity:   item.Quantity,
+						UnitPrice:  menuItem.Price,
+						OptionsKey: optionsKey,
+						Options:    options,
+					}
+					err = tx.Omit("Options.*").Create(&basketItem).Error
 				}
-				if err := tx.Create(&basketItem).Error; err != nil {
+				if err != nil {
 					tx.Rollback()
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to basket"})
 					return
 				}
 			}
 
-			tx.Commit()
-			c.JSON(http.StatusOK, gin.H{"message": "Items added to basket successfully", "basketId": basket.ID})
+			for _, combo := range basketAddRequest.Combos {
+				if combo.Quantity <= 0 {
+					tx.Rollback()
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive", "combo_id": combo.ComboID})
+					return
+				}
+
+				_, choices, err := utils.ResolveCombo(tx, combo.ComboID, combo.Choices)
+				if err != nil {
+					tx.Rollback()
+					comboErrorResponse(c, combo.ComboID, err)
+					return
+				}
+				choicesKey := utils.ChoicesKey(combo.Choices)
+
+				var basketCombo models.BasketCombo
+				err = tx.Where("basket_id = ? AND combo_id = ? AND choices_key = ?", basket.ID, combo.ComboID, choicesKey).First(&basketCombo).Error
+				if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+					tx.Rollback()
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add combo to basket"})
+					return
+				}
+				if err == nil {
+					err = tx.Model(&basketCombo).Update("quantity", basketCombo.Quantity+combo.Quantity).Error
+				} else {
+					basketCombo = models.BasketCombo{
+						BasketID:   basket.ID,
+						ComboID:    combo.ComboID,
+						Quantity:   combo.Quantity,
+						ChoicesKey: choicesKey,
+					}
+					for _, choice := range choices {
+						basketCombo.Choices = append(basketCombo.Choices, models.BasketComboChoice{SlotID: choice.Slot.ID, ItemID: choice.Item.ID})
+					}
+					err = tx.Create(&basketCombo).Error
+				}
+				if err != nil {
+					tx.Rollback()
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add combo to basket"})
+					return
+				}
+			}
+
+			if err := recalculateBasket(tx, &basket); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket total"})
+				return
+			}
+
+			response := gin.H{"message": "Items added to basket successfully", "basketId": basket.ID}
+			if err := utils.SaveIdempotentResponse(tx, c, http.StatusOK, response); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to basket"})
+				return
+			}
+			if err := tx.Commit().Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add item to basket"})
+				return
+			}
+			c.JSON(http.StatusOK, response)
 		})
 	}
 }
 
 // GetAllBasket godoc
 // @Summary Retrieve user's basket
-// @Description Retrieves all items currently in the user's basket along with total price.
+// @Description Retrieves all items and combos currently in the user's basket with their chosen options along with total price. Each item shows its current price and the price when it was added; price_changed is set when they differ. Names are in the language chosen by lang or Accept-Language. Nutrition is given per line and for the whole basket. Items containing one of the user's declared allergens are listed in warnings.
 // @Tags basket
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} struct { BasketID uint "json:\"basket_id\""; Items []map[string]interface{} "json:\"items\""; TotalPrice string "json:\"total_price\"" } "Basket contents and total price"
+// @Param lang query string false "kk, ru or en"
+// @Success 200 {object} struct { BasketID uint "json:\"basket_id\""; Items []map[string]interface{} "json:\"items\""; Combos []map[string]interface{} "json:\"combos\""; TotalPrice string "json:\"total_price\""; Nutrition models.Nutrition "json:\"nutrition\""; Warnings []string "json:\"warnings\"" } "Basket contents and total price"
 // @Failure 400 {object} map[string]interface{} "error: User ID not found"
 // @Failure 404 {object} map[string]interface{} "message: Basket not found"
 // @Failure 500 {object} map[string]interface{} "error: Failed to retrieve basket"
@@ -148,44 +263,460 @@ func GetAllBasket(router *gin.Engine) {
 			}
 
 			var basket models.Basket
-			result := initializers.DB.Preload("BasketItems.MenuItem").Where("user_id = ?", userID.(uint)).First(&basket)
+			result := initializers.DB.Preload("BasketItems", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("BasketItems.MenuItem").Preload("BasketItems.Options.Group").
+				Preload("BasketCombos", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Preload("BasketCombos.Combo").Preload("BasketCombos.Choices.Slot").Preload("BasketCombos.Choices.MenuItem").
+				Where("user_id = ?", userID.(uint)).First(&basket)
 			if result.Error != nil {
 				if errors.Is(result.Error, gorm.ErrRecordNotFound) {
-					c.JSON(http.StatusOK, gin.H{"basket_id": 0, "items": []interface{}{}, "total_price": "0.00"})
+					c.JSON(http.StatusOK, gin.H{"basket_id": 0, "items": []interface{}{}, "combos": []interface{}{}, "total_price": "0.00"})
 					return
 				}
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": result.Error.Error()})
 				return
 			}
 
-			var totalPrice decimal.Decimal = decimal.NewFromFloat(0.0)
+			locale := utils.RequestLocale(c)
+			if err := translateBasket(initializers.DB, &basket, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+				return
+			}
+			c.Header("Content-Language", locale)
+
+			menuItems := make([]models.Menu, 0, len(basket.BasketItems))
+			itemIDs := make([]uint, 0, len(basket.BasketItems))
+			for _, item := range basket.BasketItems {
+				menuItems = append(menuItems, item.MenuItem)
+				itemIDs = append(itemIDs, item.ItemID)
+			}
+			for _, combo := range basket.BasketCombos {
+				for _, choice := range combo.Choices {
+					menuItems = append(menuItems, choice.MenuItem)
+				}
+			}
+			conflicts, err := utils.AllergenConflicts(initializers.DB, userID.(uint), itemIDs)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check allergens", "details": err.Error()})
+				return
+			}
+			warnings, err := utils.AllergenWarnings(initializers.DB, userID.(uint), menuItems)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check allergens", "details": err.Error()})
+				return
+			}
+
+			var nutrition models.Nutrition
 			items := []map[string]interface{}{}
 			for _, item := range basket.BasketItems {
-				itemTotalPrice := item.MenuItem.Price.Mul(decimal.NewFromInt(int64(item.Quantity)))
+				itemNutrition := item.MenuItem.Nutrition.Times(item.Quantity)
+				nutrition = nutrition.Add(itemNutrition)
+				allergens := conflicts[item.ItemID]
+				if allergens == nil {
+					allergens = []string{}
+				}
 				items = append(items, map[string]interface{}{
-					"item_id":     item.MenuItem.ID,
-					"name":        item.MenuItem.Name,
-					"description": item.MenuItem.Description,
-					"price":       item.MenuItem.Price.String(),
-					"quantity":    item.Quantity,
-					"total_price": itemTotalPrice.String(),
+					"line_id":           item.ID,
+					"item_id":           item.MenuItem.ID,
+					"name":              item.MenuItem.Name,
+					"description":       item.MenuItem.Description,
+					"price":             item.MenuItem.Price.String(),
+					"added_price":       item.UnitPrice.String(),
+					"price_changed":     !item.UnitPrice.Equal(item.MenuItem.Price),
+					"options":           item.Options,
+					"modifiers":         utils.OptionLabels(item.Options),
+					"quantity":          item.Quantity,
+					"total_price":       item.Price.String(),
+					"nutrition":         itemNutrition,
+					"allergen_warnings": allergens,
 				})
-				totalPrice = totalPrice.Add(itemTotalPrice)
 			}
 
-			if len(items) == 0 {
+			combos := []map[string]interface{}{}
+			for _, combo := range basket.BasketCombos {
+				var comboNutrition models.Nutrition
+				choices := make([]map[string]interface{}, 0, len(combo.Choices))
+				for _, choice := range combo.Choices {
+					comboNutrition = comboNutrition.Add(choice.MenuItem.Nutrition.Times(combo.Quantity))
+					choices = append(choices, map[string]interface{}{
+						"slot":    choice.Slot.Name,
+						"item_id": choice.ItemID,
+						"name":    choice.MenuItem.Name,
+					})
+				}
+				combos = append(combos, map[string]interface{}{
+					"line_id":     combo.ID,
+					"combo_id":    combo.ComboID,
+					"name":        combo.Combo.Name,
+					"price":       combo.Combo.Price.String(),
+					"choices":     choices,
+					"quantity":    combo.Quantity,
+					"total_price": combo.Price.String(),
+					"nutrition":   comboNutrition,
+				})
+				nutrition = nutrition.Add(comboNutrition)
+			}
+
+			if len(items) == 0 && len(combos) == 0 {
 				c.JSON(http.StatusOK, gin.H{
 					"basket_id":   basket.ID,
 					"items":       items,
+					"combos":      combos,
 					"total_price": "0.00",
+					"nutrition":   nutrition,
+					"warnings":    warnings,
 				})
 			} else {
 				c.JSON(http.StatusOK, gin.H{
 					"basket_id":   basket.ID,
 					"items":       items,
-					"total_price": totalPrice.String(),
+					"combos":      combos,
+					"total_price": basket.TotalPrice.String(),
+					"nutrition":   nutrition,
+					"warnings":    warnings,
 				})
 			}
 		})
 	}
 }
+
+// Checkout godoc
+// @Summary Checkout basket
+// @Description Turns the user's basket into a new order to be picked up in the given pickup slot today. Every item, including the items chosen in combos, is checked for availability and stock, the stock is taken, the slot is booked and the basket is emptied in one transaction. Requests with an Idempotency-Key header are only handled once.
+// @Tags basket
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param checkout body CheckoutData true "Pickup slot"
+// @Param Idempotency-Key header string false "Unique key of the request; retries with the same key get the first response"
+// @Success 201 {object} models.Order "Order created"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Basket is empty or Product not found or Product is not available or Product is not served at this time or Not enough stock or Combo is not available or Invalid combo choices or Pickup slot is required or Pickup slot not found or Pickup slot is not available"
+// @Failure 409 {object} map[string]interface{} "error: Pickup slot is full or A request with this idempotency key is still being processed"
+// @Failure 422 {object} map[string]interface{} "error: Idempotency key was already used for a different request"
+// @Failure 500 {object} map[string]interface{} "error: Failed to create order or Failed to empty basket"
+// @Router /basket/checkout [post]
+func Checkout(router *gin.Engine) {
+	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
+	{
+		basketRoutes.POST("/checkout", utils.IdempotencyMiddleware(), func(c *gin.Context) {
+			userID, exists := c.Get("ID")
+			if !exists {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "User ID not found"})
+				return
+			}
+			var checkoutData CheckoutData
+			if err := c.ShouldBindJSON(&checkoutData); err != nil && !errors.Is(err, io.EOF) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			tx := initializers.DB.Begin()
+
+			var basket models.Basket
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID.(uint)).First(&basket).Error; err != nil {
+				tx.Rollback()
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Basket is empty"})
+				} else {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+				}
+				return
+			}
+
+			var basketItems []models.BasketItem
+			if err := tx.Preload("Options").Where("basket_id = ?", basket.ID).Order("id").Find(&basketItems).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+				return
+			}
+			var basketCombos []models.BasketCombo
+			if err := tx.Preload("Choices").Where("basket_id = ?", basket.ID).Order("id").Find(&basketCombos).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+				return
+			}
+			if len(basketItems) == 0 && len(basketCombos) == 0 {
+				tx.Rollback()
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Basket is empty"})
+				return
+			}
+
+			input := utils.OrderInput{SlotID: checkoutData.SlotID}
+			for _, item := range basketItems {
+				optionIDs := make([]uint, 0, len(item.Options))
+				for _, option := range item.Options {
+					optionIDs = append(optionIDs, option.ID)
+				}
+				input.Lines = append(input.Lines, utils.OrderLine{ItemID: item.ItemID, Quantity: item.Quantity, OptionIDs: optionIDs})
+			}
+			for _, combo := range basketCombos {
+				choices := make([]utils.ComboChoice, 0, len(combo.Choices))
+				for _, choice := range combo.Choices {
+					choices = append(choices, utils.ComboChoice{SlotID: choice.SlotID, ItemID: choice.ItemID})
+				}
+				input.Combos = append(input.Combos, utils.ComboLine{ComboID: combo.ComboID, Quantity: combo.Quantity, Choices: choices})
+			}
+
+			newOrder, err := utils.CreateOrder(tx, userID.(uint), input)
+			if err != nil {
+				tx.Rollback()
+				c.JSON(utils.OrderErrorResponse(err))
+				return
+			}
+
+			if err := deleteBasketItems(tx, basket.ID); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to empty basket", "details": err.Error()})
+				return
+			}
+			if err := tx.Model(&basket).Update("total_price", decimal.Zero).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to empty basket", "details": err.Error()})
+				return
+			}
+
+			if err := utils.SaveIdempotentResponse(tx, c, http.StatusCreated, newOrder); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+				return
+			}
+			if err := tx.Commit().Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+				return
+			}
+			utils.PublishOrderEvent(utils.OrderCreated, newOrder, "")
+			c.JSON(http.StatusCreated, newOrder)
+		})
+	}
+}
+
+// UpdateBasketItem godoc
+// @Summary Change quantity of a basket item
+// @Description Sets the quantity of one menu item in the user's basket.
+// @Tags basket
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "Menu item ID"
+// @Param line_id query string false "Basket line ID, required when the item is in the basket with different options"
+// @Param quantity body UpdateBasketItemData true "New quantity"
+// @Success 200 {object} map[string]interface{} "message: Basket item updated successfully, total_price"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Quantity must be positive"
+// @Failure 404 {object} map[string]interface{} "error: Item not found in basket"
+// @Failure 409 {object} map[string]interface{} "error: Item is in the basket with different options, pass line_id"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update basket item"
+// @Router /basket/items/{itemId} [patch]
+func UpdateBasketItem(router *gin.Engine) {
+	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
+	{
+		basketRoutes.PATCH("/items/:itemId", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			var updateData UpdateBasketItemData
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			if updateData.Quantity <= 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Quantity must be positive"})
+				return
+			}
+
+			tx := initializers.DB.Begin()
+
+			basket, basketItem, err := findBasketItem(tx, userID.(uint), c.Param("itemId"), c.Query("line_id"))
+			if err != nil {
+				tx.Rollback()
+				basketItemErrorResponse(c, err)
+				return
+			}
+
+			if err := tx.Model(&basketItem).Update("quantity", updateData.Quantity).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket item"})
+				return
+			}
+			if err := recalculateBasket(tx, &basket); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket total"})
+				return
+			}
+
+			tx.Commit()
+			c.JSON(http.StatusOK, gin.H{"message": "Basket item updated successfully", "total_price": basket.TotalPrice.String()})
+		})
+	}
+}
+
+// RemoveBasketItem godoc
+// @Summary Remove an item from the basket
+// @Description Removes one menu item from the user's basket.
+// @Tags basket
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "Menu item ID"
+// @Param line_id query string false "Basket line ID, required when the item is in the basket with different options"
+// @Success 200 {object} map[string]interface{} "message: Item removed from basket, total_price"
+// @Failure 404 {object} map[string]interface{} "error: Item not found in basket"
+// @Failure 409 {object} map[string]interface{} "error: Item is in the basket with different options, pass line_id"
+// @Failure 500 {object} map[string]interface{} "error: Failed to remove item from basket"
+// @Router /basket/items/{itemId} [delete]
+func RemoveBasketItem(router *gin.Engine) {
+	basketRoutes := router.Group("/basket", utils.AuthMiddleware())
+	{
+		basketRoutes.DELETE("/items/:itemId", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			tx := initializers.DB.Begin()
+
+			basket, basketItem, err := findBasketItem(tx, userID.(uint), c.Param("itemId"), c.Query("line_id"))
+			if err != nil {
+				tx.Rollback()
+				basketItemErrorResponse(c, err)
+				return
+			}
+
+			if err := tx.Delete(&basketItem).Error; err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove item from basket"})
+				return
+			}
+			if err := recalculateBasket(tx, &basket); err != nil {
+				tx.Rollback()
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update basket total"})
+				return
+			}
+
+			tx.Commit()
+			c.JSON(http.StatusOK, gin.H{"message": "Item removed from basket", "total_price": basket.TotalPrice.String()})
+		})
+	}
+}
+
+var errAmbiguousBasketItem = errors.New("several basket lines for this item")
+
+// findBasketItem locks the user's basket and returns it with the line for the
+// given menu item. When the item is in the basket with different options,
+// lineID picks the line.
+func findBasketItem(tx *gorm.DB, userID uint, itemID string, lineID string) (models.Basket, models.BasketItem, error) {
+	var basket models.Basket
+	var basketItem models.BasketItem
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).First(&basket).Error; err != nil {
+		return basket, basketItem, err
+	}
+
+	query := tx.Where("basket_id = ? AND item_id = ?", basket.ID, itemID)
+	if lineID != "" {
+		err := query.Where("id = ?", lineID).First(&basketItem).Error
+		return basket, basketItem, err
+	}
+
+	var basketItems []models.BasketItem
+	if err := query.Limit(2).Find(&basketItems).Error; err != nil {
+		return basket, basketItem, err
+	}
+	switch len(basketItems) {
+	case 0:
+		return basket, basketItem, gorm.ErrRecordNotFound
+	case 1:
+		return basket, basketItems[0], nil
+	default:
+		return basket, basketItem, errAmbiguousBasketItem
+	}
+}
+
+func basketItemErrorResponse(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found in basket"})
+		return
+	}
+	if errors.Is(err, errAmbiguousBasketItem) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Item is in the basket with different options, pass line_id"})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve basket", "details": err.Error()})
+}
+
+// translateBasket replaces the names of the basket's menu items with their
+// translations in the locale.
+func translateBasket(db *gorm.DB, basket *models.Basket, locale string) error {
+	itemIDs := []uint{}
+	for _, item := range basket.BasketItems {
+		itemIDs = append(itemIDs, item.ItemID)
+	}
+	for _, combo := range basket.BasketCombos {
+		for _, choice := range combo.Choices {
+			itemIDs = append(itemIDs, choice.ItemID)
+		}
+	}
+	translations, err := utils.MenuTranslations(db, itemIDs, locale)
+	if err != nil {
+		return err
+	}
+	for i := range basket.BasketItems {
+		menuItem := &basket.BasketItems[i].MenuItem
+		menuItem.Name, menuItem.Description = utils.TranslatedMenuItem(translations, *menuItem)
+	}
+	for i := range basket.BasketCombos {
+		for j := range basket.BasketCombos[i].Choices {
+			menuItem := &basket.BasketCombos[i].Choices[j].MenuItem
+			menuItem.Name, menuItem.Description = utils.TranslatedMenuItem(translations, *menuItem)
+		}
+	}
+	return nil
+}
+
+// recalculateBasket refreshes the stored line prices and basket total from the menu.
+func recalculateBasket(tx *gorm.DB, basket *models.Basket) error {
+	var basketItems []models.BasketItem
+	if err := tx.Preload("MenuItem").Preload("Options").Where("basket_id = ?", basket.ID).Find(&basketItems).Error; err != nil {
+		return err
+	}
+
+	totalPrice := decimal.Zero
+	for _, item := range basketItems {
+		unitPrice := item.MenuItem.Price
+		for _, option := range item.Options {
+			unitPrice = unitPrice.Add(option.PriceDelta)
+		}
+		itemTotalPrice := unitPrice.Mul(decimal.NewFromInt(int64(item.Quantity)))
+		if !itemTotalPrice.Equal(item.Price) {
+			if err := tx.Model(&models.BasketItem{}).Where("id = ?", item.ID).Update("price", itemTotalPrice).Error; err != nil {
+				return err
+			}
+		}
+		totalPrice = totalPrice.Add(itemTotalPrice)
+	}
+
+	var basketCombos []models.BasketCombo
+	if err := tx.Preload("Combo").Where("basket_id = ?", basket.ID).Find(&basketCombos).Error; err != nil {
+		return err
+	}
+	for _, combo := range basketCombos {
+		comboTotalPrice := combo.Combo.Price.Mul(decimal.NewFromInt(int64(combo.Quantity)))
+		if !comboTotalPrice.Equal(combo.Price) {
+			if err := tx.Model(&models.BasketCombo{}).Where("id = ?", combo.ID).Update("price", comboTotalPrice).Error; err != nil {
+				return err
+			}
+		}
+		totalPrice = totalPrice.Add(comboTotalPrice)
+	}
+
+	basket.TotalPrice = totalPrice
+	return tx.Model(&models.Basket{}).Where("id = ?", basket.ID).Update("total_price", totalPrice).Error
+}
+
+// deleteBasketItems removes every item and combo line of the basket. Chosen
+// options and combo choices go with their lines.
+func deleteBasketItems(tx *gorm.DB, basketID uint) error {
+	if err := tx.Where("basket_id = ?", basketID).Delete(&models.BasketItem{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("basket_id = ?", basketID).Delete(&models.BasketCombo{}).Error
+}
+
+type CheckoutData struct {
+	SlotID uint `json:"slot_id" example:"3"`
+}
+
+type UpdateBasketItemData struct {
+	Quantity int `json:"quantity" binding:"required"`
+}
@@ -0,0 +1,233 @@
+package category
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// GetAllCategories godoc
+// @Summary Get all categories
+// @Description Retrieves all menu categories in display order.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param lang query string false "kk, ru or en"
+// @Success 200 {object} map[string]interface{} "categories: list of categories"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve categories"
+// @Router /categories [get]
+func GetAllCategories(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.GET("/", func(c *gin.Context) {
+			var categories []models.Category
+			if err := initializers.DB.Order("sort_order, id").Find(&categories).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+				return
+			}
+			locale := utils.RequestLocale(c)
+			if err := utils.TranslateCategories(initializers.DB, categories, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve categories"})
+				return
+			}
+			c.Header("Content-Language", locale)
+			c.JSON(http.StatusOK, gin.H{"categories": categories})
+		})
+	}
+}
+
+// GetCategoryItems godoc
+// @Summary Get menu items of a category
+// @Description Retrieves the category together with its menu items.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Param lang query string false "kk, ru or en"
+// @Success 200 {object} map[string]interface{} "category, menuItems"
+// @Failure 404 {object} map[string]interface{} "error: Category not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve menu items"
+// @Router /categories/{id}/items [get]
+func GetCategoryItems(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.GET("/:id/items", func(c *gin.Context) {
+			var category models.Category
+			if err := initializers.DB.First(&category, c.Param("id")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+
+			var menuItems []models.Menu
+			if err := initializers.DB.Preload("Tags").Where("category_id = ?", category.ID).Order("id").Find(&menuItems).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			locale := utils.RequestLocale(c)
+			categories := []models.Category{category}
+			if err := utils.TranslateCategories(initializers.DB, categories, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			if err := utils.TranslateMenuItems(initializers.DB, menuItems, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			c.Header("Content-Language", locale)
+			c.JSON(http.StatusOK, gin.H{"category": categories[0], "menuItems": menuItems})
+		})
+	}
+}
+
+// AddCategory godoc
+// @Summary Add a category
+// @Description Adds a new menu category, accessible only by admin users.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param category body models.Category true "Category to be added"
+// @Success 201 {object} map[string]interface{} "message: Category added successfully, categoryId"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 500 {object} map[string]interface{} "error: Failed to add category"
+// @Router /categories [post]
+func AddCategory(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.POST("/", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var category models.Category
+			if err := c.BindJSON(&category); err != nil || category.Name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			category.ID = 0
+			category.Items = nil
+			if err := initializers.DB.Create(&category).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add category", "details": err.Error()})
+				return
+			}
+			c.JSON(http.StatusCreated, gin.H{"message": "Category added successfully", "categoryId": category.ID})
+		})
+	}
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Renames or reorders a category, accessible only by admin users.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Param updates body UpdateCategoryData true "Fields to update"
+// @Success 200 {object} map[string]interface{} "message: Category updated successfully"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Category not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update category"
+// @Router /categories/{id} [patch]
+func UpdateCategory(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.PATCH("/:id", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var updateData UpdateCategoryData
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			updates := map[string]interface{}{}
+			if updateData.Name != nil {
+				if *updateData.Name == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+					return
+				}
+				updates["name"] = *updateData.Name
+			}
+			if updateData.SortOrder != nil {
+				updates["sort_order"] = *updateData.SortOrder
+			}
+			if len(updates) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			result := initializers.DB.Model(&models.Category{}).Where("id = ?", c.Param("id")).Updates(updates)
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category", "details": result.Error.Error()})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Category updated successfully"})
+		})
+	}
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Deletes a category, accessible only by admin users. Its menu items are kept without a category.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Success 200 {object} map[string]interface{} "message: Category deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Category not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete category"
+// @Router /categories/{id} [delete]
+func DeleteCategory(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.DELETE("/:id", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var category models.Category
+			if err := initializers.DB.First(&category, c.Param("id")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Unscoped().Model(&models.Menu{}).Where("category_id = ?", category.ID).Update("category_id", nil).Error; err != nil {
+					return err
+				}
+				if err := tx.Where("category_id = ?", category.ID).Delete(&models.CategoryTranslation{}).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&category).Error
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+		})
+	}
+}
+
+type UpdateCategoryData struct {
+	Name      *string `json:"name"`
+	SortOrder *int    `json:"sort_order"`
+}
@@ -0,0 +1,146 @@
+package category
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"net/http"
+)
+
+// GetCategoryTranslations godoc
+// @Summary Get translations of a category
+// @Description Lists the names of a category in every translated locale, accessible only by admin users.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Success 200 {array} models.CategoryTranslation "Translations"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Category not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve translations"
+// @Router /categories/{id}/translations [get]
+func GetCategoryTranslations(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.GET("/:id/translations", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var category models.Category
+			if err := initializers.DB.First(&category, c.Param("id")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+
+			translations := []models.CategoryTranslation{}
+			if err := initializers.DB.Where("category_id = ?", category.ID).Order("locale").Find(&translations).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve translations"})
+				return
+			}
+			c.JSON(http.StatusOK, translations)
+		})
+	}
+}
+
+// SetCategoryTranslation godoc
+// @Summary Set a translation of a category
+// @Description Creates or replaces the name of a category in one locale, accessible only by admin users.
+// @Tags categories
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Param locale path string true "kk, ru or en"
+// @Param translation body CategoryTranslationData true "Translated name"
+// @Success 200 {object} models.CategoryTranslation "Translation"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Unsupported locale"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Category not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to save translation"
+// @Router /categories/{id}/translations/{locale} [put]
+func SetCategoryTranslation(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.PUT("/:id/translations/:locale", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			locale := c.Param("locale")
+			if !utils.SupportedLocale(locale) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+				return
+			}
+			var data CategoryTranslationData
+			if err := c.BindJSON(&data); err != nil || data.Name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var category models.Category
+			if err := initializers.DB.First(&category, c.Param("id")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+				return
+			}
+
+			translation := models.CategoryTranslation{CategoryID: category.ID, Locale: locale, Name: data.Name}
+			err := initializers.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "category_id"}, {Name: "locale"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name"}),
+			}).Create(&translation).Error
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation"})
+				return
+			}
+			c.JSON(http.StatusOK, translation)
+		})
+	}
+}
+
+// DeleteCategoryTranslation godoc
+// @Summary Delete a translation of a category
+// @Description Removes the translation of a category in one locale, accessible only by admin users.
+// @Tags categories
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path string true "Category ID"
+// @Param locale path string true "kk, ru or en"
+// @Success 200 {object} map[string]interface{} "message: Translation deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Translation not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete translation"
+// @Router /categories/{id}/translations/{locale} [delete]
+func DeleteCategoryTranslation(router *gin.Engine) {
+	categoryRoutes := router.Group("/categories", utils.AuthMiddleware())
+	{
+		categoryRoutes.DELETE("/:id/translations/:locale", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			result := initializers.DB.Where("category_id = ? AND locale = ?", c.Param("id"), c.Param("locale")).Delete(&models.CategoryTranslation{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Translation deleted successfully"})
+		})
+	}
+}
+
+type CategoryTranslationData struct {
+	Name string `json:"name" example:"Сорпалар"`
+}
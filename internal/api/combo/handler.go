@@ -0,0 +1,257 @@
+package combo
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// GetAllCombos godoc
+// @Summary Get all combos
+// @Description Lists the combo meals with their slots and the menu items that can be chosen for each slot. Non-admin users only see available combos.
+// @Tags combos
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "combos: list of combos"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve combos"
+// @Router /combos [get]
+func GetAllCombos(router *gin.Engine) {
+	comboRoutes := router.Group("/combos", utils.AuthMiddleware())
+	{
+		comboRoutes.GET("/", func(c *gin.Context) {
+			query := initializers.DB.Scopes(comboSlotsInOrder).Order("id")
+			if role, _ := c.Get("role"); role != "admin" {
+				query = query.Where("is_available = ?", true)
+			}
+
+			combos := []models.Combo{}
+			if err := query.Find(&combos).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve combos"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"combos": combos})
+		})
+	}
+}
+
+// AddCombo godoc
+// @Summary Add a combo
+// @Description Adds a combo meal with its fixed price and slots, accessible only by admin users. Each slot lists the menu items a customer can choose from.
+// @Tags combos
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param combo body ComboData true "Combo with slots"
+// @Success 201 {object} models.Combo "Created combo"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Menu item not found"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 500 {object} map[string]interface{} "error: Failed to add combo"
+// @Router /combos [post]
+func AddCombo(router *gin.Engine) {
+	comboRoutes := router.Group("/combos", utils.AuthMiddleware())
+	{
+		comboRoutes.POST("/", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var data ComboData
+			if err := c.BindJSON(&data); err != nil || data.Name == "" || data.Price.IsNegative() || len(data.Slots) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			combo := models.Combo{
+				Name:        data.Name,
+				Description: data.Description,
+				Price:       data.Price,
+				IsAvailable: data.IsAvailable == nil || *data.IsAvailable,
+			}
+			for i, slotData := range data.Slots {
+				if slotData.Name == "" || len(slotData.ItemIDs) == 0 {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+					return
+				}
+				var items []models.Menu
+				if err := initializers.DB.Where("id IN ?", slotData.ItemIDs).Find(&items).Error; err != nil {
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add combo"})
+					return
+				}
+				if len(items) != len(uniqueIDs(slotData.ItemIDs)) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Menu item not found", "slot": slotData.Name})
+					return
+				}
+				sortOrder := i
+				if slotData.SortOrder != nil {
+					sortOrder = *slotData.SortOrder
+				}
+				combo.Slots = append(combo.Slots, models.ComboSlot{Name: slotData.Name, SortOrder: sortOrder, Items: items})
+			}
+
+			if err := initializers.DB.Create(&combo).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add combo", "details": err.Error()})
+				return
+			}
+			c.JSON(http.StatusCreated, combo)
+		})
+	}
+}
+
+// UpdateCombo godoc
+// @Summary Update a combo
+// @Description Changes the name, description, price or availability of a combo, accessible only by admin users. Basket totals pick up the new price; placed orders keep theirs.
+// @Tags combos
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param comboId path string true "Combo ID"
+// @Param updates body UpdateComboData true "Fields to update"
+// @Success 200 {object} map[string]interface{} "message: Combo updated successfully"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Combo not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update combo"
+// @Router /combos/{comboId} [patch]
+func UpdateCombo(router *gin.Engine) {
+	comboRoutes := router.Group("/combos", utils.AuthMiddleware())
+	{
+		comboRoutes.PATCH("/:comboId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var updateData UpdateComboData
+			if err := c.BindJSON(&updateData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			updates := map[string]interface{}{}
+			if updateData.Name != nil {
+				if *updateData.Name == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+					return
+				}
+				updates["name"] = *updateData.Name
+			}
+			if updateData.Description != nil {
+				updates["description"] = *updateData.Description
+			}
+			if updateData.Price != nil {
+				if updateData.Price.IsNegative() {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+					return
+				}
+				updates["price"] = *updateData.Price
+			}
+			if updateData.IsAvailable != nil {
+				updates["is_available"] = *updateData.IsAvailable
+			}
+			if len(updates) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			result := initializers.DB.Model(&models.Combo{}).Where("id = ?", c.Param("comboId")).Updates(updates)
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update combo", "details": result.Error.Error()})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Combo not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Combo updated successfully"})
+		})
+	}
+}
+
+// DeleteCombo godoc
+// @Summary Delete a combo
+// @Description Deletes a combo and removes it from baskets, accessible only by admin users. Combos that were ordered cannot be deleted and should be made unavailable instead.
+// @Tags combos
+// @Produce json
+// @Security ApiKeyAuth
+// @Param comboId path string true "Combo ID"
+// @Success 200 {object} map[string]interface{} "message: Combo deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Combo not found"
+// @Failure 409 {object} map[string]interface{} "error: Combo has been ordered, make it unavailable instead"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete combo"
+// @Router /combos/{comboId} [delete]
+func DeleteCombo(router *gin.Engine) {
+	comboRoutes := router.Group("/combos", utils.AuthMiddleware())
+	{
+		comboRoutes.DELETE("/:comboId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var combo models.Combo
+			if err := initializers.DB.First(&combo, c.Param("comboId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Combo not found"})
+				return
+			}
+
+			var orderCount int64
+			if err := initializers.DB.Model(&models.OrderCombo{}).Where("combo_id = ?", combo.ID).Count(&orderCount).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete combo"})
+				return
+			}
+			if orderCount > 0 {
+				c.JSON(http.StatusConflict, gin.H{"error": "Combo has been ordered, make it unavailable instead"})
+				return
+			}
+
+			// Its slots and the basket lines holding it are deleted with the
+			// combo by their foreign keys.
+			if err := initializers.DB.Delete(&combo).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete combo"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Combo deleted successfully"})
+		})
+	}
+}
+
+func comboSlotsInOrder(db *gorm.DB) *gorm.DB {
+	return db.Preload("Slots", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).Preload("Slots.Items")
+}
+
+func uniqueIDs(ids []uint) map[uint]struct{} {
+	unique := make(map[uint]struct{}, len(ids))
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+	return unique
+}
+
+type ComboData struct {
+	Name        string          `json:"name" example:"Business lunch"`
+	Description string          `json:"description"`
+	Price       decimal.Decimal `json:"price" swaggertype:"string" example:"2500"`
+	IsAvailable *bool           `json:"is_available"`
+	Slots       []ComboSlotData `json:"slots"`
+}
+
+type ComboSlotData struct {
+	Name      string `json:"name" example:"Soup"`
+	SortOrder *int   `json:"sort_order"`
+	ItemIDs   []uint `json:"item_ids"`
+}
+
+type UpdateComboData struct {
+	Name        *string          `json:"name"`
+	Description *string          `json:"description"`
+	Price       *decimal.Decimal `json:"price" swaggertype:"string"`
+	IsAvailable *bool            `json:"is_available"`
+}
@@ -0,0 +1,146 @@
+package kitchen
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// GetKitchenQueue godoc
+// @Summary Get the kitchen queue
+// @Description Lists the orders being prepared by pickup slot, earliest first, with their items, modifiers and combo choices. Orders without a slot are placed by when they were made. Accessible only by admin users.
+// @Tags kitchen
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {array} map[string]interface{} "Orders being prepared"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve kitchen queue"
+// @Router /kitchen/orders [get]
+func GetKitchenQueue(router *gin.Engine) {
+	kitchenRoutes := router.Group("/kitchen", utils.AuthMiddleware())
+	{
+		kitchenRoutes.GET("/orders", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			orders, err := kitchenOrders(initializers.DB.Where("order_status = ?", models.Preparing))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve kitchen queue"})
+				return
+			}
+			response := make([]map[string]interface{}, 0, len(orders))
+			for _, order := range orders {
+				response = append(response, kitchenOrder(order))
+			}
+			c.JSON(http.StatusOK, response)
+		})
+	}
+}
+
+// StreamKitchenOrders godoc
+// @Summary Stream kitchen order events
+// @Description Server-Sent Events stream of order changes for the kitchen display, accessible only by admin users. An order.created event carries the new order as listed in the kitchen queue; order.status and order.deleted events carry the order id with its old and new status. A ping event is sent every 25 seconds and the stream ends with an expired event when the access token expires. Clients that cannot set the Authorization header pass a ticket from /stream/ticket instead.
+// @Tags kitchen
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param ticket query string false "Stream ticket, for clients that cannot set headers"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized or Invalid stream ticket"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Router /kitchen/stream [get]
+func StreamKitchenOrders(router *gin.Engine) {
+	kitchenRoutes := router.Group("/kitchen", utils.StreamAuthMiddleware())
+	{
+		kitchenRoutes.GET("/stream", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			events, unsubscribe := utils.Events.Subscribe(utils.KitchenTopic)
+			defer unsubscribe()
+			utils.StreamEvents(c, events, kitchenEvent)
+		})
+	}
+}
+
+// kitchenEvent returns what the kitchen display is sent for the event. New
+// orders are sent in full so the display does not have to fetch them.
+func kitchenEvent(event utils.Event) interface{} {
+	orderEvent, ok := event.Data.(utils.OrderEvent)
+	if !ok || event.Type != utils.OrderCreated {
+		return event.Data
+	}
+	orders, err := kitchenOrders(initializers.DB.Where("id = ?", orderEvent.OrderID))
+	if err != nil || len(orders) == 0 {
+		return event.Data
+	}
+	return kitchenOrder(orders[0])
+}
+
+// kitchenOrders loads the orders selected by query, the ones to be picked up
+// first coming first, with what the kitchen needs to prepare them.
+func kitchenOrders(query *gorm.DB) ([]models.Order, error) {
+	var orders []models.Order
+	err := query.
+		Preload("User").
+		Preload("OrderDetails", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("OrderDetails.MenuItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).
+		Preload("OrderDetails.Modifiers").
+		Preload("OrderCombos", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("OrderCombos.Choices").
+		Order("COALESCE(pickup_at, created_at), id").
+		Find(&orders).Error
+	return orders, err
+}
+
+func kitchenOrder(order models.Order) map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(order.OrderDetails))
+	for _, detail := range order.OrderDetails {
+		items = append(items, map[string]interface{}{
+			"item_id":   detail.ItemID,
+			"name":      detail.MenuItem.Name,
+			"quantity":  detail.Quantity,
+			"modifiers": detail.ModifierLabels(),
+		})
+	}
+	combos := make([]map[string]interface{}, 0, len(order.OrderCombos))
+	for _, combo := range order.OrderCombos {
+		choices := make([]map[string]interface{}, 0, len(combo.Choices))
+		for _, choice := range combo.Choices {
+			choices = append(choices, map[string]interface{}{
+				"slot":    choice.SlotName,
+				"item_id": choice.ItemID,
+				"name":    choice.ItemName,
+			})
+		}
+		combos = append(combos, map[string]interface{}{
+			"combo_id": combo.ComboID,
+			"name":     combo.Name,
+			"quantity": combo.Quantity,
+			"choices":  choices,
+		})
+	}
+	var pickupAt interface{}
+	if order.PickupAt != nil {
+		pickupAt = order.PickupAt.Format(time.RFC3339)
+	}
+	return map[string]interface{}{
+		"order_id":      order.ID,
+		"order_status":  order.OrderStatus,
+		"pickup_number": order.PickupNumber,
+		"pickup_at":     pickupAt,
+		"customer":      order.User.Username,
+		"items":         items,
+		"combos":        combos,
+		"created_at":    order.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
@@ -1,33 +1,85 @@
 package menu
 
 import (
+	"errors"
 	"final_project/initializers"
 	"final_project/internal/models"
 	"final_project/internal/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 )
 
 // GetAllMenu godoc
 // @Summary Get all menu items
-// @Description Retrieves all available menu items from the database.
+// @Description Retrieves one page of menu items. Clients only see available items unless the available filter is given. Names and descriptions are in the language chosen by lang or Accept-Language. Each item carries its average Rating and RatingCount from visible reviews.
 // @Tags menu
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
-// @Success 200 {object} struct { MenuItems []models.Menu } "A list of all menu items"
+// @Param q query string false "Search in name and description, in any language"
+// @Param category query string false "Category ID or name"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param available query bool false "Filter by availability"
+// @Param tags query string false "Comma separated tags every item must have, e.g. vegan,halal"
+// @Param exclude query string false "Comma separated tags no item may have, e.g. nuts,gluten"
+// @Param for_me query bool false "Hide items conflicting with the user's allergens and diets"
+// @Param archived query bool false "List archived items instead, admin only"
+// @Param sort query string false "price, name, popularity or rating, prefix with - for descending" default(name)
+// @Param page query int false "Page number" default(1)
+// @Param page_size query int false "Items per page, at most 100" default(20)
+// @Param lang query string false "kk, ru or en"
+// @Success 200 {object} struct { MenuItems []models.Menu; Pagination Pagination } "A page of menu items"
+// @Failure 400 {object} map[string]interface{} "error: Invalid filter, sort or pagination parameter"
 // @Failure 500 {object} map[string]interface{} "error: Failed to retrieve menu items"
 // @Router /menu [get]
 func GetAllMenu(router *gin.Engine) {
 	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
 	{
 		menuRoutes.GET("/", func(c *gin.Context) {
-			var menuItems []models.Menu
-			if err := initializers.DB.Find(&menuItems).Error; err != nil {
+			query, err := applyMenuFilters(c, initializers.DB.Model(&models.Menu{}))
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			pagination, err := parsePagination(c)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			var total int64
+			if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			pagination.setTotal(total)
+
+			query, err = applyMenuSort(c, query)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			menuItems := []models.Menu{}
+			if err := query.Select("menus.*").Preload("Tags").Preload("ModifierGroups", modifierGroupsInOrder).Offset(pagination.offset()).Limit(pagination.PageSize).Find(&menuItems).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"menuItems": menuItems})
+			locale := utils.RequestLocale(c)
+			if err := utils.TranslateMenuItems(initializers.DB, menuItems, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			if err := utils.RateMenuItems(initializers.DB, menuItems); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			c.Header("Content-Language", locale)
+			c.JSON(http.StatusOK, gin.H{"menuItems": menuItems, "pagination": pagination})
 		})
 	}
 }
@@ -41,7 +93,7 @@ func GetAllMenu(router *gin.Engine) {
 // @Security ApiKeyAuth
 // @Param menuItem body models.Menu true "Menu Item to be added"
 // @Success 201 {object} map[string]interface{} "message: Menu item added successfully, menuItemId"
-// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Category not found"
 // @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
 // @Failure 500 {object} map[string]interface{} "error: Failed to add menu item"
 // @Router /menu [post]
@@ -56,7 +108,25 @@ func AddMenu(router *gin.Engine) {
 					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 					return
 				}
-				if err := initializers.DB.Create(&menuItem).Error; err != nil {
+				if menuItem.CategoryID != nil {
+					if err := initializers.DB.First(&models.Category{}, *menuItem.CategoryID).Error; err != nil {
+						c.JSON(http.StatusBadRequest, gin.H{"error": "Category not found"})
+						return
+					}
+					menuItem.Category = nil
+				}
+				if err := menuItem.Nutrition.Validate(); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+					return
+				}
+				userID, _ := c.Get("ID")
+				err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Create(&menuItem).Error; err != nil {
+						return err
+					}
+					return utils.RecordPriceChange(tx, menuItem.ID, decimal.Zero, menuItem.Price, userID.(uint))
+				})
+				if err != nil {
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add menu item"})
 					return
 				}
@@ -71,7 +141,7 @@ func AddMenu(router *gin.Engine) {
 
 // UpdateMenu godoc
 // @Summary Update a menu item
-// @Description Updates details of a specific menu item, accessible only by admin users.
+// @Description Updates details of a specific menu item, accessible only by admin users. Price changes are recorded in the price history. Nutrition values must not be negative.
 // @Tags menu
 // @Accept json
 // @Produce json
@@ -100,15 +170,43 @@ func UpdateMenu(router *gin.Engine) {
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
 				return
 			}
-
-			result := initializers.DB.Model(&models.Menu{}).Where("id = ?", itemId).Updates(updates)
-			if result.Error != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item", "details": result.Error.Error()})
-				return
+			// Nutrition may be sent nested as on the item itself; its fields
+			// are plain columns.
+			if nutrition, ok := updates["Nutrition"].(map[string]interface{}); ok {
+				delete(updates, "Nutrition")
+				for column, value := range nutrition {
+					updates[column] = value
+				}
 			}
 
-			if result.RowsAffected == 0 {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+			userID, _ := c.Get("ID")
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				var menuItem models.Menu
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&menuItem, itemId).Error; err != nil {
+					return err
+				}
+				oldPrice := menuItem.Price
+				if err := tx.Model(&models.Menu{}).Where("id = ?", menuItem.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				if err := tx.First(&menuItem, menuItem.ID).Error; err != nil {
+					return err
+				}
+				if err := menuItem.Nutrition.Validate(); err != nil {
+					return err
+				}
+				return utils.RecordPriceChange(tx, menuItem.ID, oldPrice, menuItem.Price, userID.(uint))
+			})
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+					return
+				}
+				if errors.Is(err, models.ErrInvalidNutrition) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": err.Error()})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item", "details": err.Error()})
 				return
 			}
 
@@ -118,8 +216,8 @@ func UpdateMenu(router *gin.Engine) {
 }
 
 // DeleteMenu godoc
-// @Summary Delete a menu item
-// @Description Deletes a specific menu item from the database, accessible only by admin users.
+// @Summary Archive a menu item
+// @Description Archives a menu item, accessible only by admin users. The item is hidden from the menu and removed from baskets, but kept for the orders that refer to it and can be restored.
 // @Tags menu
 // @Accept json
 // @Produce json
@@ -127,6 +225,7 @@ func UpdateMenu(router *gin.Engine) {
 // @Param itemId path string true "ID of the Menu Item to delete"
 // @Success 200 {object} map[string]interface{} "message: Menu item deleted successfully"
 // @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
 // @Failure 500 {object} map[string]interface{} "error: Failed to delete menu item"
 // @Router /menu/{itemId} [delete]
 func DeleteMenu(router *gin.Engine) {
@@ -139,7 +238,21 @@ func DeleteMenu(router *gin.Engine) {
 				return
 			}
 			itemId := c.Param("itemId")
-			if err := initializers.DB.Delete(&models.Menu{}, itemId).Error; err != nil {
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				var menuItem models.Menu
+				if err := tx.First(&menuItem, itemId).Error; err != nil {
+					return err
+				}
+				if err := tx.Delete(&menuItem).Error; err != nil {
+					return err
+				}
+				return removeFromBaskets(tx, menuItem.ID)
+			})
+			if err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+					return
+				}
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete menu item"})
 				return
 			}
@@ -147,3 +260,139 @@ func DeleteMenu(router *gin.Engine) {
 		})
 	}
 }
+
+// RestoreMenu godoc
+// @Summary Restore an archived menu item
+// @Description Brings an archived menu item back to the menu, accessible only by admin users.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the archived Menu Item"
+// @Success 200 {object} map[string]interface{} "message: Menu item restored successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 409 {object} map[string]interface{} "error: Menu item is not archived"
+// @Failure 500 {object} map[string]interface{} "error: Failed to restore menu item"
+// @Router /menu/{itemId}/restore [post]
+func RestoreMenu(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/:itemId/restore", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.Unscoped().First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+			if !menuItem.DeletedAt.Valid {
+				c.JSON(http.StatusConflict, gin.H{"error": "Menu item is not archived"})
+				return
+			}
+
+			if err := initializers.DB.Unscoped().Model(&menuItem).Update("deleted_at", nil).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore menu item"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Menu item restored successfully"})
+		})
+	}
+}
+
+// removeFromBaskets drops the basket lines and combos that use an archived
+// item and refreshes the totals of the baskets they were in.
+func removeFromBaskets(tx *gorm.DB, itemID uint) error {
+	var itemBasketIDs, comboBasketIDs, basketComboIDs []uint
+	if err := tx.Model(&models.BasketItem{}).Where("item_id = ?", itemID).Pluck("basket_id", &itemBasketIDs).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.BasketComboChoice{}).Where("item_id = ?", itemID).Pluck("basket_combo_id", &basketComboIDs).Error; err != nil {
+		return err
+	}
+	if len(basketComboIDs) > 0 {
+		if err := tx.Model(&models.BasketCombo{}).Where("id IN ?", basketComboIDs).Pluck("basket_id", &comboBasketIDs).Error; err != nil {
+			return err
+		}
+	}
+	basketIDs := append(itemBasketIDs, comboBasketIDs...)
+	if len(basketIDs) == 0 {
+		return nil
+	}
+
+	if len(itemBasketIDs) > 0 {
+		if err := tx.Where("item_id = ?", itemID).Delete(&models.BasketItem{}).Error; err != nil {
+			return err
+		}
+	}
+	if len(basketComboIDs) > 0 {
+		if err := tx.Where("id IN ?", basketComboIDs).Delete(&models.BasketCombo{}).Error; err != nil {
+			return err
+		}
+	}
+
+	// Line prices are stored, so the totals are their sums.
+	return tx.Exec(`UPDATE baskets SET total_price =
+		COALESCE((SELECT SUM(price) FROM basket_items WHERE basket_items.basket_id = baskets.id), 0) +
+		COALESCE((SELECT SUM(price) FROM basket_combos WHERE basket_combos.basket_id = baskets.id), 0)
+		WHERE id IN ?`, basketIDs).Error
+}
+
+// SetMenuTags godoc
+// @Summary Set allergen and dietary tags of a menu item
+// @Description Replaces the tags of a menu item, accessible only by admin users.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param tags body MenuTagsData true "Tag names"
+// @Success 200 {object} map[string]interface{} "message: Menu item tags updated successfully, tags"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Unknown tag"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update menu item tags"
+// @Router /menu/{itemId}/tags [put]
+func SetMenuTags(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.PUT("/:itemId/tags", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var tagsData MenuTagsData
+			if err := c.BindJSON(&tagsData); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			tags, err := utils.FindTags(initializers.DB, tagsData.Tags, "")
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+
+			if err := initializers.DB.Model(&menuItem).Association("Tags").Replace(tags); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item tags"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Menu item tags updated successfully", "tags": tags})
+		})
+	}
+}
+
+type MenuTagsData struct {
+	Tags []string `json:"tags"`
+}
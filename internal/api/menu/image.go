@@ -0,0 +1,148 @@
+package menu
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/storage"
+	"final_project/internal/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+)
+
+const (
+	maxImageUploadSize = 10 << 20
+	fullImageSize      = 1200
+	thumbnailSize      = 300
+)
+
+// UploadMenuImage godoc
+// @Summary Upload a menu item image
+// @Description Uploads a JPEG or PNG image for a menu item, accessible only by admin users. A full-size and a thumbnail variant are stored and replace the previous image.
+// @Tags menu
+// @Accept multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param image formData file true "JPEG or PNG image, at most 10 MB"
+// @Success 200 {object} map[string]interface{} "message: Menu item image uploaded successfully, image_url, thumbnail_url"
+// @Failure 400 {object} map[string]interface{} "error: Image file is required or Image dimensions are too large or Failed to process image"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 413 {object} map[string]interface{} "error: Image is too large"
+// @Failure 415 {object} map[string]interface{} "error: Unsupported image type, expected JPEG or PNG"
+// @Failure 500 {object} map[string]interface{} "error: Failed to store image"
+// @Router /menu/{itemId}/image [post]
+func UploadMenuImage(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/:itemId/image", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadSize)
+			fileHeader, err := c.FormFile("image")
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Image is too large"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+				return
+			}
+			file, err := fileHeader.Open()
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+				return
+			}
+			defer file.Close()
+			data, err := io.ReadAll(file)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Image file is required"})
+				return
+			}
+
+			// The declared content type is not trusted, the bytes are sniffed instead.
+			if _, ok := storage.DetectImageType(data); !ok {
+				c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Unsupported image type, expected JPEG or PNG"})
+				return
+			}
+
+			full, err := storage.ResizeImage(data, fullImageSize)
+			if err != nil {
+				if errors.Is(err, storage.ErrImageTooLarge) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Image dimensions are too large"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to process image", "details": err.Error()})
+				return
+			}
+			thumbnail, err := storage.ResizeImage(data, thumbnailSize)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to process image", "details": err.Error()})
+				return
+			}
+
+			sum := sha256.Sum256(data)
+			baseKey := fmt.Sprintf("menu/%d/%s", menuItem.ID, hex.EncodeToString(sum[:8]))
+			imageURL, err := initializers.Images.Put(baseKey+"_full"+full.Extension, full.Data)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+				return
+			}
+			thumbnailURL, err := initializers.Images.Put(baseKey+"_thumb"+thumbnail.Extension, thumbnail.Data)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+				return
+			}
+
+			oldImageURL, oldThumbnailURL := menuItem.ImageURL, menuItem.ThumbnailURL
+			err = initializers.DB.Model(&menuItem).Updates(map[string]interface{}{
+				"image_url":     imageURL,
+				"thumbnail_url": thumbnailURL,
+			}).Error
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item", "details": err.Error()})
+				return
+			}
+
+			// Re-uploading the same file yields the same URLs, which must be kept.
+			if oldImageURL != "" && oldImageURL != imageURL {
+				initializers.Images.Delete(oldImageURL)
+			}
+			if oldThumbnailURL != "" && oldThumbnailURL != thumbnailURL {
+				initializers.Images.Delete(oldThumbnailURL)
+			}
+
+			c.JSON(http.StatusOK, gin.H{"message": "Menu item image uploaded successfully", "image_url": imageURL, "thumbnail_url": thumbnailURL})
+		})
+	}
+}
+
+// ServeImages serves images kept in local storage. File names contain a
+// hash of the upload, so they can be cached for good.
+func ServeImages(router *gin.Engine) {
+	local, ok := initializers.Images.(*storage.LocalStorage)
+	if !ok {
+		return
+	}
+	images := router.Group(local.URLPrefix, func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Next()
+	})
+	images.Static("/", local.Dir)
+}
@@ -0,0 +1,164 @@
+package menu
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// GetMenuModifiers godoc
+// @Summary Get modifier groups of a menu item
+// @Description Lists the variant, extra and removal groups of a menu item with their options and price deltas.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Success 200 {array} models.ModifierGroup "Modifier groups"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve modifiers"
+// @Router /menu/{itemId}/modifiers [get]
+func GetMenuModifiers(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/:itemId/modifiers", func(c *gin.Context) {
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			groups := []models.ModifierGroup{}
+			if err := initializers.DB.Scopes(modifierGroupsInOrder).Where("item_id = ?", menuItem.ID).Find(&groups).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve modifiers"})
+				return
+			}
+			c.JSON(http.StatusOK, groups)
+		})
+	}
+}
+
+// AddMenuModifierGroup godoc
+// @Summary Add a modifier group to a menu item
+// @Description Adds a group of options to a menu item, accessible only by admin users. Variant groups (e.g. size) must select exactly one option; extra and removal groups select between min_select and max_select options.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param group body ModifierGroupData true "Modifier group with options"
+// @Success 201 {object} models.ModifierGroup "Created group"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Failed to add modifier group"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Router /menu/{itemId}/modifiers [post]
+func AddMenuModifierGroup(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/:itemId/modifiers", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			var data ModifierGroupData
+			if err := c.BindJSON(&data); err != nil || data.Name == "" || len(data.Options) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			group := models.ModifierGroup{
+				ItemID:    menuItem.ID,
+				Name:      data.Name,
+				Kind:      models.ModifierKind(data.Kind),
+				MinSelect: data.MinSelect,
+				MaxSelect: data.MaxSelect,
+				SortOrder: data.SortOrder,
+			}
+			if group.Kind == models.Variant {
+				group.MinSelect, group.MaxSelect = 1, 1
+			}
+			for _, option := range data.Options {
+				if option.Name == "" {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+					return
+				}
+				group.Options = append(group.Options, models.ModifierOption{Name: option.Name, PriceDelta: option.PriceDelta})
+			}
+
+			if err := initializers.DB.Create(&group).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to add modifier group", "details": err.Error()})
+				return
+			}
+			c.JSON(http.StatusCreated, group)
+		})
+	}
+}
+
+// DeleteMenuModifierGroup godoc
+// @Summary Delete a modifier group of a menu item
+// @Description Removes a modifier group and its options, accessible only by admin users. Placed orders keep their copy of the chosen options; basket lines lose them.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param groupId path string true "ID of the modifier group"
+// @Success 200 {object} map[string]interface{} "message: Modifier group deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Modifier group not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete modifier group"
+// @Router /menu/{itemId}/modifiers/{groupId} [delete]
+func DeleteMenuModifierGroup(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.DELETE("/:itemId/modifiers/:groupId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var group models.ModifierGroup
+			if err := initializers.DB.Where("id = ? AND item_id = ?", c.Param("groupId"), c.Param("itemId")).First(&group).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Modifier group not found"})
+				return
+			}
+
+			// Its options and the basket lines' choices of them are deleted
+			// with the group by their foreign keys.
+			if err := initializers.DB.Delete(&group).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete modifier group"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Modifier group deleted successfully"})
+		})
+	}
+}
+
+func modifierGroupsInOrder(db *gorm.DB) *gorm.DB {
+	return db.Preload("Options", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).Order("sort_order, id")
+}
+
+type ModifierGroupData struct {
+	Name      string               `json:"name" example:"Size"`
+	Kind      string               `json:"kind" example:"variant"`
+	MinSelect int                  `json:"min_select"`
+	MaxSelect int                  `json:"max_select"`
+	SortOrder int                  `json:"sort_order"`
+	Options   []ModifierOptionData `json:"options"`
+}
+
+type ModifierOptionData struct {
+	Name       string          `json:"name" example:"large"`
+	PriceDelta decimal.Decimal `json:"price_delta" swaggertype:"string" example:"300"`
+}
@@ -0,0 +1,86 @@
+package menu
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// GetMenuPriceHistory godoc
+// @Summary Get price history of a menu item
+// @Description Lists the price changes of a menu item, oldest first, accessible only by admin users.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} map[string]interface{} "item_id, current_price, changes"
+// @Failure 400 {object} map[string]interface{} "error: Invalid date"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve price history"
+// @Router /menu/{itemId}/prices [get]
+func GetMenuPriceHistory(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/:itemId/prices", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			from, errFrom := parseDate(c.Query("from"))
+			to, errTo := parseDate(c.Query("to"))
+			if errFrom != nil || errTo != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			query := initializers.DB.Preload("ChangedBy").Where("item_id = ?", menuItem.ID)
+			if from != nil {
+				query = query.Where("created_at >= ?", *from)
+			}
+			if to != nil {
+				query = query.Where("created_at < ?", to.AddDate(0, 0, 1))
+			}
+			var history []models.MenuPriceHistory
+			if err := query.Order("created_at, id").Find(&history).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve price history"})
+				return
+			}
+
+			changes := make([]map[string]interface{}, 0, len(history))
+			for _, entry := range history {
+				var changedBy map[string]interface{}
+				if entry.ChangedBy != nil {
+					changedBy = map[string]interface{}{
+						"id":       entry.ChangedBy.ID,
+						"username": entry.ChangedBy.Username,
+					}
+				}
+				changes = append(changes, map[string]interface{}{
+					"old_price":  entry.OldPrice.String(),
+					"new_price":  entry.NewPrice.String(),
+					"changed_by": changedBy,
+					"changed_at": entry.CreatedAt.Format(time.RFC3339Nano),
+				})
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"item_id":       menuItem.ID,
+				"current_price": menuItem.Price.String(),
+				"changes":       changes,
+			})
+		})
+	}
+}
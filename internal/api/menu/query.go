@@ -0,0 +1,180 @@
+package menu
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type Pagination struct {
+	Page       int   `json:"page"`
+	PageSize   int   `json:"page_size"`
+	Total      int64 `json:"total"`
+	TotalPages int   `json:"total_pages"`
+}
+
+// sortOrders maps the sort query parameter to ORDER BY clauses. A leading "-"
+// sorts descending.
+var sortOrders = map[string]string{
+	"price":       "menus.price ASC, menus.id",
+	"-price":      "menus.price DESC, menus.id",
+	"name":        "menus.name ASC, menus.id",
+	"-name":       "menus.name DESC, menus.id",
+	"popularity":  "COALESCE(popularity.sold, 0) DESC, menus.id",
+	"-popularity": "COALESCE(popularity.sold, 0) ASC, menus.id",
+	"rating":      "COALESCE(ratings.average, 0) DESC, COALESCE(ratings.count, 0) DESC, menus.id",
+	"-rating":     "COALESCE(ratings.average, 0) ASC, COALESCE(ratings.count, 0) ASC, menus.id",
+}
+
+// applyMenuFilters narrows query by the search, category, price,
+// availability and tag parameters of the menu listing.
+func applyMenuFilters(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		pattern := "%" + search + "%"
+		query = query.Where("menus.name ILIKE ? OR menus.description ILIKE ? OR menus.id IN (?)", pattern, pattern,
+			initializers.DB.Model(&models.MenuTranslation{}).Select("item_id").Where("name ILIKE ? OR description ILIKE ?", pattern, pattern))
+	}
+
+	if category := c.Query("category"); category != "" {
+		if categoryID, err := strconv.ParseUint(category, 10, 64); err == nil {
+			query = query.Where("menus.category_id = ?", categoryID)
+		} else {
+			query = query.Where("menus.category_id IN (?)", initializers.DB.Model(&models.Category{}).Select("id").Where("LOWER(name) = LOWER(?)", category))
+		}
+	}
+
+	if minPrice := c.Query("min_price"); minPrice != "" {
+		price, err := decimal.NewFromString(minPrice)
+		if err != nil {
+			return nil, errors.New("Invalid min_price")
+		}
+		query = query.Where("menus.price >= ?", price)
+	}
+	if maxPrice := c.Query("max_price"); maxPrice != "" {
+		price, err := decimal.NewFromString(maxPrice)
+		if err != nil {
+			return nil, errors.New("Invalid max_price")
+		}
+		query = query.Where("menus.price <= ?", price)
+	}
+
+	// Archived items are only listed for admins who ask for them.
+	if archived := c.Query("archived"); archived != "" {
+		showArchived, err := strconv.ParseBool(archived)
+		if err != nil {
+			return nil, errors.New("Invalid archived filter")
+		}
+		if role, _ := c.Get("role"); showArchived && role == "admin" {
+			query = query.Unscoped().Where("menus.deleted_at IS NOT NULL")
+		}
+	}
+
+	// Clients only see what they can order unless they ask otherwise.
+	if available := c.Query("available"); available != "" {
+		isAvailable, err := strconv.ParseBool(available)
+		if err != nil {
+			return nil, errors.New("Invalid available filter")
+		}
+		query = query.Where("menus.is_available = ?", isAvailable)
+	} else if role, _ := c.Get("role"); role != "admin" {
+		query = query.Where("menus.is_available = ?", true)
+	}
+
+	for _, tag := range splitList(c.Query("tags")) {
+		query = query.Where("menus.id IN (?)", taggedItems().Where("tags.name = ?", tag))
+	}
+	if exclude := splitList(c.Query("exclude")); len(exclude) > 0 {
+		query = query.Where("menus.id NOT IN (?)", taggedItems().Where("tags.name IN ?", exclude))
+	}
+
+	if forMe := c.Query("for_me"); forMe != "" {
+		personalized, err := strconv.ParseBool(forMe)
+		if err != nil {
+			return nil, errors.New("Invalid for_me filter")
+		}
+		if personalized {
+			userID, _ := c.Get("ID")
+			query = query.
+				Where("menus.id NOT IN (?)", initializers.DB.Table("menu_tags").Select("menu_tags.menu_id").
+					Joins("JOIN user_allergens ON user_allergens.tag_id = menu_tags.tag_id").
+					Where("user_allergens.user_id = ?", userID)).
+				Where("NOT EXISTS (?)", initializers.DB.Table("user_diets").Select("1").
+					Where("user_diets.user_id = ?", userID).
+					Where("user_diets.tag_id NOT IN (SELECT menu_tags.tag_id FROM menu_tags WHERE menu_tags.menu_id = menus.id)"))
+		}
+	}
+
+	return query, nil
+}
+
+// taggedItems selects the ids of menu items joined with their tags.
+func taggedItems() *gorm.DB {
+	return initializers.DB.Table("menu_tags").Select("menu_tags.menu_id").Joins("JOIN tags ON tags.id = menu_tags.tag_id")
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.ToLower(strings.TrimSpace(item)); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// applyMenuSort orders query by the sort query parameter, name by default.
+func applyMenuSort(c *gin.Context, query *gorm.DB) (*gorm.DB, error) {
+	sort := c.DefaultQuery("sort", "name")
+	order, ok := sortOrders[sort]
+	if !ok {
+		return nil, errors.New("Invalid sort, expected one of price, name, popularity, rating optionally prefixed with -")
+	}
+	if strings.HasSuffix(sort, "popularity") {
+		// Canceled orders were never sold.
+		query = query.Joins(`LEFT JOIN (SELECT order_details.item_id, SUM(order_details.quantity) AS sold FROM order_details
+			JOIN orders ON orders.id = order_details.order_id WHERE orders.order_status <> ?
+			GROUP BY order_details.item_id) AS popularity ON popularity.item_id = menus.id`, models.Canceled)
+	}
+	if strings.HasSuffix(sort, "rating") {
+		query = query.Joins("LEFT JOIN (" + utils.VisibleRatings + ") AS ratings ON ratings.item_id = menus.id")
+	}
+	return query.Order(order), nil
+}
+
+// parsePagination reads page and page_size, defaulting to the first page of
+// defaultPageSize items.
+func parsePagination(c *gin.Context) (Pagination, error) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		return Pagination{}, errors.New("Invalid page")
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("page_size", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		return Pagination{}, errors.New("Invalid page_size")
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return Pagination{Page: page, PageSize: pageSize}, nil
+}
+
+func (p *Pagination) setTotal(total int64) {
+	p.Total = total
+	p.TotalPages = int((total + int64(p.PageSize) - 1) / int64(p.PageSize))
+}
+
+func (p Pagination) offset() int {
+	return (p.Page - 1) * p.PageSize
+}
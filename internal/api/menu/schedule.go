@@ -0,0 +1,234 @@
+package menu
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// GetTodayMenu godoc
+// @Summary Get the menu served now
+// @Description Retrieves the available menu items whose schedule covers the current weekday, date and meal period. Items without a schedule are always served.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param lang query string false "kk, ru or en"
+// @Success 200 {object} map[string]interface{} "menuItems, meal_periods"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve menu items"
+// @Router /menu/today [get]
+func GetTodayMenu(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/today", func(c *gin.Context) {
+			now := time.Now()
+
+			menuItems := []models.Menu{}
+			err := initializers.DB.Preload("Tags").Preload("ModifierGroups", modifierGroupsInOrder).
+				Where("menus.is_available = ?", true).
+				Where(utils.ScheduledAt(now)).
+				Order("menus.name, menus.id").
+				Find(&menuItems).Error
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			locale := utils.RequestLocale(c)
+			if err := utils.TranslateMenuItems(initializers.DB, menuItems, locale); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve menu items"})
+				return
+			}
+			c.Header("Content-Language", locale)
+			c.JSON(http.StatusOK, gin.H{"menuItems": menuItems, "meal_periods": models.MealPeriodsAt(now)})
+		})
+	}
+}
+
+// GetMenuSchedules godoc
+// @Summary Get schedules of a menu item
+// @Description Lists when a menu item is served. An item without schedules is served all the time.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Success 200 {array} ScheduleData "Schedules"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve schedules"
+// @Router /menu/{itemId}/schedules [get]
+func GetMenuSchedules(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/:itemId/schedules", func(c *gin.Context) {
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			var schedules []models.MenuSchedule
+			if err := initializers.DB.Where("item_id = ?", menuItem.ID).Order("id").Find(&schedules).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve schedules"})
+				return
+			}
+
+			response := make([]ScheduleData, 0, len(schedules))
+			for _, schedule := range schedules {
+				response = append(response, scheduleData(schedule))
+			}
+			c.JSON(http.StatusOK, response)
+		})
+	}
+}
+
+// AddMenuSchedule godoc
+// @Summary Add a schedule to a menu item
+// @Description Adds a serving window to a menu item, accessible only by admin users. Empty weekdays mean every day, missing dates mean no limit and an empty meal period means all day.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param schedule body ScheduleData true "Schedule"
+// @Success 201 {object} ScheduleData "Created schedule"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Invalid weekday or Invalid date"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to add schedule"
+// @Router /menu/{itemId}/schedules [post]
+func AddMenuSchedule(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/:itemId/schedules", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			var data ScheduleData
+			if err := c.BindJSON(&data); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			schedule := models.MenuSchedule{
+				ItemID:     menuItem.ID,
+				MealPeriod: models.MealPeriod(strings.ToLower(data.MealPeriod)),
+			}
+			for _, name := range data.Weekdays {
+				weekday, ok := weekdayNames[strings.ToLower(name)]
+				if !ok {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weekday", "weekday": name})
+					return
+				}
+				schedule.Weekdays |= 1 << uint(weekday)
+			}
+			var err error
+			if schedule.StartDate, err = parseDate(data.StartDate); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date", "start_date": data.StartDate})
+				return
+			}
+			if schedule.EndDate, err = parseDate(data.EndDate); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date", "end_date": data.EndDate})
+				return
+			}
+
+			if err := initializers.DB.Create(&schedule).Error; err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to add schedule", "details": err.Error()})
+				return
+			}
+			c.JSON(http.StatusCreated, scheduleData(schedule))
+		})
+	}
+}
+
+// DeleteMenuSchedule godoc
+// @Summary Delete a schedule of a menu item
+// @Description Removes a serving window from a menu item, accessible only by admin users.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param scheduleId path string true "ID of the schedule"
+// @Success 200 {object} map[string]interface{} "message: Schedule deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Schedule not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete schedule"
+// @Router /menu/{itemId}/schedules/{scheduleId} [delete]
+func DeleteMenuSchedule(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.DELETE("/:itemId/schedules/:scheduleId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			result := initializers.DB.Where("id = ? AND item_id = ?", c.Param("scheduleId"), c.Param("itemId")).Delete(&models.MenuSchedule{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete schedule"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Schedule not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+		})
+	}
+}
+
+func parseDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	return &date, nil
+}
+
+func scheduleData(schedule models.MenuSchedule) ScheduleData {
+	data := ScheduleData{ID: schedule.ID, Weekdays: []string{}, MealPeriod: string(schedule.MealPeriod)}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if schedule.Weekdays&(1<<uint(day)) != 0 {
+			data.Weekdays = append(data.Weekdays, strings.ToLower(day.String()))
+		}
+	}
+	if schedule.StartDate != nil {
+		data.StartDate = schedule.StartDate.Format("2006-01-02")
+	}
+	if schedule.EndDate != nil {
+		data.EndDate = schedule.EndDate.Format("2006-01-02")
+	}
+	return data
+}
+
+type ScheduleData struct {
+	ID         uint     `json:"id"`
+	Weekdays   []string `json:"weekdays" example:"monday,wednesday"`
+	StartDate  string   `json:"start_date" example:"2024-09-01"`
+	EndDate    string   `json:"end_date" example:"2024-12-31"`
+	MealPeriod string   `json:"meal_period" example:"lunch"`
+}
@@ -0,0 +1,624 @@
+package menu
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const maxImportSize = 5 << 20
+
+// menuColumns are the CSV columns of the import and export, in export order.
+var menuColumns = []string{"name", "description", "price", "quantity", "is_available", "category", "tags", "kcal", "protein", "fat", "carbs", "portion_grams"}
+
+// nutritionColumns are the menu columns that hold nutrition values.
+var nutritionColumns = []string{"kcal", "protein", "fat", "carbs", "portion_grams"}
+
+var errInvalidImport = errors.New("import has invalid rows")
+
+// ImportMenu godoc
+// @Summary Import menu items
+// @Description Creates or updates menu items from a CSV or JSON file, accessible only by admin users. Items are matched by name, case-insensitively; fields missing from the file are left unchanged on existing items, and archived items are restored. New items need a price and are available unless is_available says otherwise. CSV files have a header row with any of the columns name, description, price, quantity, is_available, category, tags (separated by ;), kcal, protein, fat, carbs, portion_grams. Nothing is saved if any row is invalid; with dry_run the rows are only checked.
+// @Tags menu
+// @Accept text/csv,json,multipart/form-data
+// @Produce json
+// @Security ApiKeyAuth
+// @Param format query string false "csv or json, taken from the content type or file name when omitted"
+// @Param dry_run query bool false "Only validate the rows"
+// @Param file formData file false "CSV or JSON file, when uploading a form"
+// @Success 200 {object} ImportReport "Per-row result"
+// @Failure 400 {object} map[string]interface{} "error: Unsupported format, use csv or json or Invalid file or Import has invalid rows"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 413 {object} map[string]interface{} "error: File is too large"
+// @Failure 500 {object} map[string]interface{} "error: Failed to import menu"
+// @Router /menu/import [post]
+func ImportMenu(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/import", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			dryRun := false
+			if value := c.Query("dry_run"); value != "" {
+				var err error
+				if dryRun, err = strconv.ParseBool(value); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+					return
+				}
+			}
+
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+			data, format, err := readImportFile(c)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "File is too large"})
+					return
+				}
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file", "details": err.Error()})
+				return
+			}
+
+			var rows []importRow
+			switch format {
+			case "csv":
+				rows, err = parseMenuCSV(data)
+			case "json":
+				rows, err = parseMenuJSON(data)
+			default:
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, use csv or json"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file", "details": err.Error()})
+				return
+			}
+
+			userID, _ := c.Get("ID")
+			var report ImportReport
+			if dryRun {
+				report, err = importMenu(initializers.DB, rows, userID.(uint), false)
+			} else {
+				err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+					var err error
+					report, err = importMenu(tx, rows, userID.(uint), true)
+					return err
+				})
+			}
+			report.DryRun = dryRun
+			if err != nil {
+				if errors.Is(err, errInvalidImport) {
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Import has invalid rows", "report": report})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import menu", "details": err.Error()})
+				return
+			}
+			c.JSON(http.StatusOK, report)
+		})
+	}
+}
+
+// ExportMenu godoc
+// @Summary Export menu items
+// @Description Downloads every menu item that is not archived as CSV or JSON, in the format accepted by the import. Accessible only by admin users.
+// @Tags menu
+// @Produce text/csv,json
+// @Security ApiKeyAuth
+// @Param format query string false "csv or json" default(csv)
+// @Success 200 {array} MenuRecord "Menu items"
+// @Failure 400 {object} map[string]interface{} "error: Unsupported format, use csv or json"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 500 {object} map[string]interface{} "error: Failed to export menu"
+// @Router /menu/export [get]
+func ExportMenu(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/export", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			format := strings.ToLower(c.DefaultQuery("format", "csv"))
+			if format != "csv" && format != "json" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported format, use csv or json"})
+				return
+			}
+
+			var menuItems []models.Menu
+			if err := initializers.DB.Preload("Category").Preload("Tags", func(db *gorm.DB) *gorm.DB { return db.Order("name") }).Order("id").Find(&menuItems).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export menu"})
+				return
+			}
+			records := make([]MenuRecord, 0, len(menuItems))
+			for _, item := range menuItems {
+				records = append(records, menuRecord(item))
+			}
+
+			c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=menu.%s", format))
+			if format == "json" {
+				c.JSON(http.StatusOK, records)
+				return
+			}
+
+			var buf bytes.Buffer
+			writer := csv.NewWriter(&buf)
+			writer.Write(menuColumns)
+			for _, record := range records {
+				writer.Write([]string{
+					record.Name,
+					*record.Description,
+					record.Price.String(),
+					strconv.Itoa(*record.Quantity),
+					strconv.FormatBool(*record.IsAvailable),
+					*record.Category,
+					strings.Join(record.Tags, ";"),
+					strconv.FormatFloat(*record.Kcal, 'f', -1, 64),
+					strconv.FormatFloat(*record.Protein, 'f', -1, 64),
+					strconv.FormatFloat(*record.Fat, 'f', -1, 64),
+					strconv.FormatFloat(*record.Carbs, 'f', -1, 64),
+					strconv.FormatFloat(*record.PortionGrams, 'f', -1, 64),
+				})
+			}
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export menu"})
+				return
+			}
+			c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+		})
+	}
+}
+
+// MenuRecord is a menu item as it is imported and exported. A nil field is
+// not touched when an existing item is updated.
+type MenuRecord struct {
+	Name         string           `json:"name"`
+	Description  *string          `json:"description,omitempty"`
+	Price        *decimal.Decimal `json:"price,omitempty" swaggertype:"string"`
+	Quantity     *int             `json:"quantity,omitempty"`
+	IsAvailable  *bool            `json:"is_available,omitempty"`
+	Category     *string          `json:"category,omitempty"`
+	Tags         []string         `json:"tags"`
+	Kcal         *float64         `json:"kcal,omitempty"`
+	Protein      *float64         `json:"protein,omitempty"`
+	Fat          *float64         `json:"fat,omitempty"`
+	Carbs        *float64         `json:"carbs,omitempty"`
+	PortionGrams *float64         `json:"portion_grams,omitempty"`
+}
+
+// nutritionFields maps the nutrition columns to the record's values.
+func (r *MenuRecord) nutritionFields() map[string]**float64 {
+	return map[string]**float64{
+		"kcal":          &r.Kcal,
+		"protein":       &r.Protein,
+		"fat":           &r.Fat,
+		"carbs":         &r.Carbs,
+		"portion_grams": &r.PortionGrams,
+	}
+}
+
+type ImportReport struct {
+	DryRun   bool           `json:"dry_run"`
+	Created  int            `json:"created"`
+	Updated  int            `json:"updated"`
+	Restored int            `json:"restored"`
+	Invalid  int            `json:"invalid"`
+	Rows     []ImportResult `json:"rows"`
+}
+
+type ImportResult struct {
+	Row    int      `json:"row"`
+	Name   string   `json:"name"`
+	Action string   `json:"action,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// importRow is a parsed record with the errors found while parsing it.
+type importRow struct {
+	Row    int
+	Record MenuRecord
+	Errors []string
+}
+
+// readImportFile returns the uploaded file, either the request body or the
+// "file" field of a form, and its format.
+func readImportFile(c *gin.Context) ([]byte, string, error) {
+	format := strings.ToLower(c.Query("format"))
+	if c.ContentType() == "multipart/form-data" {
+		fileHeader, err := c.FormFile("file")
+		if err != nil {
+			return nil, "", err
+		}
+		if format == "" {
+			format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fileHeader.Filename)), ".")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer file.Close()
+		data, err := io.ReadAll(file)
+		return data, format, err
+	}
+
+	if format == "" {
+		switch c.ContentType() {
+		case "text/csv", "application/csv":
+			format = "csv"
+		case "application/json":
+			format = "json"
+		}
+	}
+	data, err := io.ReadAll(c.Request.Body)
+	return data, format, err
+}
+
+func parseMenuCSV(data []byte) ([]importRow, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("missing header row")
+	}
+
+	columns := map[string]int{}
+	for i, column := range records[0] {
+		column = strings.ToLower(strings.TrimSpace(column))
+		known := false
+		for _, name := range menuColumns {
+			known = known || name == column
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		columns[column] = i
+	}
+	if _, ok := columns["name"]; !ok {
+		return nil, errors.New("missing name column")
+	}
+
+	rows := make([]importRow, 0, len(records)-1)
+	for i, record := range records[1:] {
+		// Row numbers match the spreadsheet, where the header is row 1.
+		row := importRow{Row: i + 2}
+		cell := func(column string) (string, bool) {
+			index, ok := columns[column]
+			if !ok {
+				return "", false
+			}
+			if index >= len(record) {
+				return "", true
+			}
+			return strings.TrimSpace(record[index]), true
+		}
+
+		row.Record.Name, _ = cell("name")
+		if value, ok := cell("description"); ok {
+			row.Record.Description = &value
+		}
+		if value, ok := cell("price"); ok && value != "" {
+			price, err := decimal.NewFromString(value)
+			if err != nil {
+				row.Errors = append(row.Errors, "invalid price")
+			} else {
+				row.Record.Price = &price
+			}
+		}
+		if value, ok := cell("quantity"); ok && value != "" {
+			quantity, err := strconv.Atoi(value)
+			if err != nil {
+				row.Errors = append(row.Errors, "invalid quantity")
+			} else {
+				row.Record.Quantity = &quantity
+			}
+		}
+		if value, ok := cell("is_available"); ok && value != "" {
+			isAvailable, err := strconv.ParseBool(value)
+			if err != nil {
+				row.Errors = append(row.Errors, "invalid is_available")
+			} else {
+				row.Record.IsAvailable = &isAvailable
+			}
+		}
+		if value, ok := cell("category"); ok {
+			row.Record.Category = &value
+		}
+		if value, ok := cell("tags"); ok {
+			row.Record.Tags = strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' })
+			if row.Record.Tags == nil {
+				row.Record.Tags = []string{}
+			}
+		}
+		fields := row.Record.nutritionFields()
+		for _, column := range nutritionColumns {
+			if value, ok := cell(column); ok && value != "" {
+				number, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					row.Errors = append(row.Errors, "invalid "+column)
+				} else {
+					*fields[column] = &number
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseMenuJSON(data []byte) ([]importRow, error) {
+	var records []MenuRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	rows := make([]importRow, 0, len(records))
+	for i, record := range records {
+		rows = append(rows, importRow{Row: i + 1, Record: record})
+	}
+	return rows, nil
+}
+
+// importMenu validates every row and, when apply is set and all rows are
+// valid, creates or updates the menu items. It returns errInvalidImport
+// together with the report when a row is invalid.
+func importMenu(tx *gorm.DB, rows []importRow, userID uint, apply bool) (ImportReport, error) {
+	report := ImportReport{Rows: make([]ImportResult, 0, len(rows))}
+	type plannedRow struct {
+		record   MenuRecord
+		existing *models.Menu
+		category *models.Category
+		tags     []models.Tag
+	}
+	planned := make([]plannedRow, 0, len(rows))
+	seen := map[string]int{}
+
+	for _, row := range rows {
+		record := row.Record
+		record.Name = strings.TrimSpace(record.Name)
+		result := ImportResult{Row: row.Row, Name: record.Name, Errors: row.Errors}
+		plan := plannedRow{record: record}
+
+		if record.Name == "" {
+			result.Errors = append(result.Errors, "name is required")
+		} else if first, ok := seen[strings.ToLower(record.Name)]; ok {
+			result.Errors = append(result.Errors, fmt.Sprintf("duplicate of row %d", first))
+		} else {
+			seen[strings.ToLower(record.Name)] = row.Row
+			// Archived items are matched too, so that importing one does not
+			// add a second item of the same name.
+			var existing models.Menu
+			err := tx.Unscoped().Where("LOWER(name) = LOWER(?)", record.Name).Order("deleted_at IS NOT NULL, id").First(&existing).Error
+			if err == nil {
+				plan.existing = &existing
+			} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return report, err
+			}
+		}
+
+		if record.Price != nil && record.Price.IsNegative() {
+			result.Errors = append(result.Errors, "price must not be negative")
+		}
+		if record.Price == nil && plan.existing == nil && !containsError(row.Errors, "invalid price") {
+			result.Errors = append(result.Errors, "price is required for new items")
+		}
+		if record.Quantity != nil && *record.Quantity < 0 {
+			result.Errors = append(result.Errors, "quantity must not be negative")
+		}
+		fields := record.nutritionFields()
+		for _, column := range nutritionColumns {
+			if value := *fields[column]; value != nil && *value < 0 {
+				result.Errors = append(result.Errors, column+" must not be negative")
+			}
+		}
+		if record.PortionGrams != nil && *record.PortionGrams != float64(int(*record.PortionGrams)) {
+			result.Errors = append(result.Errors, "portion_grams must be a whole number")
+		}
+		if record.Category != nil && strings.TrimSpace(*record.Category) != "" {
+			var category models.Category
+			err := tx.Where("LOWER(name) = LOWER(?)", strings.TrimSpace(*record.Category)).First(&category).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				result.Errors = append(result.Errors, fmt.Sprintf("unknown category %q", *record.Category))
+			} else if err != nil {
+				return report, err
+			} else {
+				plan.category = &category
+			}
+		}
+		if record.Tags != nil {
+			tags, err := utils.FindTags(tx, record.Tags, "")
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+			plan.tags = tags
+		}
+
+		if len(result.Errors) > 0 {
+			report.Invalid++
+		} else if plan.existing != nil && plan.existing.DeletedAt.Valid {
+			result.Action = "restore"
+			report.Restored++
+		} else if plan.existing != nil {
+			result.Action = "update"
+			report.Updated++
+		} else {
+			result.Action = "create"
+			report.Created++
+		}
+		report.Rows = append(report.Rows, result)
+		planned = append(planned, plan)
+	}
+
+	if report.Invalid > 0 {
+		return report, errInvalidImport
+	}
+	if !apply {
+		return report, nil
+	}
+
+	for _, plan := range planned {
+		record := plan.record
+		var categoryID *uint
+		if plan.category != nil {
+			categoryID = &plan.category.ID
+		}
+
+		if plan.existing == nil {
+			menuItem := models.Menu{
+				Name:        record.Name,
+				Price:       *record.Price,
+				IsAvailable: true,
+				CategoryID:  categoryID,
+			}
+			if record.Description != nil {
+				menuItem.Description = *record.Description
+			}
+			if record.Quantity != nil {
+				menuItem.Quantity = *record.Quantity
+			}
+			if record.IsAvailable != nil {
+				menuItem.IsAvailable = *record.IsAvailable
+			}
+			if record.Kcal != nil {
+				menuItem.Nutrition.Kcal = *record.Kcal
+			}
+			if record.Protein != nil {
+				menuItem.Nutrition.Protein = *record.Protein
+			}
+			if record.Fat != nil {
+				menuItem.Nutrition.Fat = *record.Fat
+			}
+			if record.Carbs != nil {
+				menuItem.Nutrition.Carbs = *record.Carbs
+			}
+			if record.PortionGrams != nil {
+				menuItem.Nutrition.PortionGrams = int(*record.PortionGrams)
+			}
+			if err := tx.Create(&menuItem).Error; err != nil {
+				return report, err
+			}
+			if err := utils.RecordPriceChange(tx, menuItem.ID, decimal.Zero, menuItem.Price, userID); err != nil {
+				return report, err
+			}
+			if len(plan.tags) > 0 {
+				if err := tx.Model(&menuItem).Association("Tags").Replace(plan.tags); err != nil {
+					return report, err
+				}
+			}
+			continue
+		}
+
+		menuItem := *plan.existing
+		updates := map[string]interface{}{}
+		if record.Description != nil {
+			updates["description"] = *record.Description
+		}
+		if record.Price != nil {
+			updates["price"] = *record.Price
+		}
+		if record.Quantity != nil {
+			updates["quantity"] = *record.Quantity
+		}
+		if record.IsAvailable != nil {
+			updates["is_available"] = *record.IsAvailable
+		}
+		if record.Category != nil {
+			updates["category_id"] = categoryID
+		}
+		for column, value := range nutritionUpdates(record) {
+			updates[column] = value
+		}
+		if menuItem.DeletedAt.Valid {
+			updates["deleted_at"] = nil
+		}
+		if len(updates) > 0 {
+			if err := tx.Unscoped().Model(&models.Menu{}).Where("id = ?", menuItem.ID).Updates(updates).Error; err != nil {
+				return report, err
+			}
+		}
+		if record.Price != nil {
+			if err := utils.RecordPriceChange(tx, menuItem.ID, menuItem.Price, *record.Price, userID); err != nil {
+				return report, err
+			}
+		}
+		if record.Tags != nil {
+			if err := tx.Model(&menuItem).Association("Tags").Replace(plan.tags); err != nil {
+				return report, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// nutritionUpdates returns the nutrition columns set on the record.
+func nutritionUpdates(record MenuRecord) map[string]interface{} {
+	updates := map[string]interface{}{}
+	for column, value := range record.nutritionFields() {
+		if *value == nil {
+			continue
+		}
+		if column == "portion_grams" {
+			updates[column] = int(**value)
+		} else {
+			updates[column] = **value
+		}
+	}
+	return updates
+}
+
+func containsError(errs []string, message string) bool {
+	for _, err := range errs {
+		if err == message {
+			return true
+		}
+	}
+	return false
+}
+
+func menuRecord(item models.Menu) MenuRecord {
+	category := ""
+	if item.Category != nil {
+		category = item.Category.Name
+	}
+	tags := make([]string, 0, len(item.Tags))
+	for _, tag := range item.Tags {
+		tags = append(tags, tag.Name)
+	}
+	portionGrams := float64(item.Nutrition.PortionGrams)
+	return MenuRecord{
+		Name:         item.Name,
+		Description:  &item.Description,
+		Price:        &item.Price,
+		Quantity:     &item.Quantity,
+		IsAvailable:  &item.IsAvailable,
+		Category:     &category,
+		Tags:         tags,
+		Kcal:         &item.Nutrition.Kcal,
+		Protein:      &item.Nutrition.Protein,
+		Fat:          &item.Nutrition.Fat,
+		Carbs:        &item.Nutrition.Carbs,
+		PortionGrams: &portionGrams,
+	}
+}
@@ -0,0 +1,147 @@
+package menu
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"net/http"
+)
+
+// GetMenuTranslations godoc
+// @Summary Get translations of a menu item
+// @Description Lists the names and descriptions of a menu item in every translated locale, accessible only by admin users.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Success 200 {array} models.MenuTranslation "Translations"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve translations"
+// @Router /menu/{itemId}/translations [get]
+func GetMenuTranslations(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/:itemId/translations", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			translations := []models.MenuTranslation{}
+			if err := initializers.DB.Where("item_id = ?", menuItem.ID).Order("locale").Find(&translations).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve translations"})
+				return
+			}
+			c.JSON(http.StatusOK, translations)
+		})
+	}
+}
+
+// SetMenuTranslation godoc
+// @Summary Set a translation of a menu item
+// @Description Creates or replaces the name and description of a menu item in one locale, accessible only by admin users.
+// @Tags menu
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param locale path string true "kk, ru or en"
+// @Param translation body MenuTranslationData true "Translated name and description"
+// @Success 200 {object} models.MenuTranslation "Translation"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Unsupported locale"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to save translation"
+// @Router /menu/{itemId}/translations/{locale} [put]
+func SetMenuTranslation(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.PUT("/:itemId/translations/:locale", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			locale := c.Param("locale")
+			if !utils.SupportedLocale(locale) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported locale"})
+				return
+			}
+			var data MenuTranslationData
+			if err := c.BindJSON(&data); err != nil || data.Name == "" {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			translation := models.MenuTranslation{ItemID: menuItem.ID, Locale: locale, Name: data.Name, Description: data.Description}
+			err := initializers.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "item_id"}, {Name: "locale"}},
+				DoUpdates: clause.AssignmentColumns([]string{"name", "description"}),
+			}).Create(&translation).Error
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save translation"})
+				return
+			}
+			c.JSON(http.StatusOK, translation)
+		})
+	}
+}
+
+// DeleteMenuTranslation godoc
+// @Summary Delete a translation of a menu item
+// @Description Removes the translation of a menu item in one locale, accessible only by admin users. The default locale is shown instead.
+// @Tags menu
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "ID of the Menu Item"
+// @Param locale path string true "kk, ru or en"
+// @Success 200 {object} map[string]interface{} "message: Translation deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Translation not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete translation"
+// @Router /menu/{itemId}/translations/{locale} [delete]
+func DeleteMenuTranslation(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.DELETE("/:itemId/translations/:locale", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			result := initializers.DB.Where("item_id = ? AND locale = ?", c.Param("itemId"), c.Param("locale")).Delete(&models.MenuTranslation{})
+			if result.Error != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete translation"})
+				return
+			}
+			if result.RowsAffected == 0 {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Translation not found"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Translation deleted successfully"})
+		})
+	}
+}
+
+type MenuTranslationData struct {
+	Name        string `json:"name" example:"Палау"`
+	Description string `json:"description"`
+}
@@ -1,30 +1,36 @@
 package order
 
 import (
+	"errors"
 	"final_project/initializers"
 	"final_project/internal/models"
 	"final_project/internal/utils"
+	"fmt"
 	"github.com/gin-gonic/gin"
-	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"net/http"
 	"time"
 )
 
 // @Summary Add a new order
-// @Description Creates a new order with specified items.
+// @Description Creates a new order with specified items and combos, to be picked up in the given pickup slot today. Every slot of a combo must be filled with one of its items. Requests with an Idempotency-Key header are only handled once.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
 // @Param order body OrderRequest true "Order details"
+// @Param Idempotency-Key header string false "Unique key of the request; retries with the same key get the first response"
 // @Success 201 {object} models.Order "Order created"
-// @Failure 400 {object} map[string]interface{} "error: Invalid request or Product not found or Not enough stock"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Order has no items or Invalid quantity or Product not found or Product is not available or Product is not served at this time or Not enough stock or Invalid modifiers or Combo not found or Combo is not available or Invalid combo choices or Pickup slot is required or Pickup slot not found or Pickup slot is not available"
+// @Failure 409 {object} map[string]interface{} "error: Pickup slot is full or A request with this idempotency key is still being processed"
+// @Failure 422 {object} map[string]interface{} "error: Idempotency key was already used for a different request"
 // @Failure 500 {object} map[string]interface{} "error: Failed to create order"
 // @Router /orders [post]
 func AddOrder(router *gin.Engine) {
 	orders := router.Group("/orders", utils.AuthMiddleware())
 	{
-		orders.POST("/", func(c *gin.Context) {
+		orders.POST("/", utils.IdempotencyMiddleware(), func(c *gin.Context) {
 			userID, _ := c.Get("ID")
 			var orderReq OrderRequest
 			if err := c.BindJSON(&orderReq); err != nil {
@@ -32,70 +38,44 @@ func AddOrder(router *gin.Engine) {
 				return
 			}
 
-			newOrder := models.Order{
-				UserID:       userID.(uint),
-				OrderDetails: []models.OrderDetail{},
-				CreatedAt:    time.Now(),
-				OrderStatus:  models.Preparing,
+			input := utils.OrderInput{SlotID: orderReq.SlotID}
+			for _, item := range orderReq.OrderItems {
+				input.Lines = append(input.Lines, utils.OrderLine{ItemID: item.ProductID, Quantity: item.Quantity, OptionIDs: item.Options})
+			}
+			for _, combo := range orderReq.Combos {
+				input.Combos = append(input.Combos, utils.ComboLine{ComboID: combo.ComboID, Quantity: combo.Quantity, Choices: combo.Choices})
 			}
 
-			var totalPrice decimal.Decimal
 			tx := initializers.DB.Begin()
-
-			for _, item := range orderReq.OrderItems {
-				menuItem := models.Menu{}
-				result := tx.First(&menuItem, item.ProductID)
-				if result.Error != nil {
-					tx.Rollback()
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Product not found", "productID": item.ProductID})
-					return
-				}
-
-				if menuItem.Quantity < item.Quantity {
-					tx.Rollback()
-					c.JSON(http.StatusBadRequest, gin.H{"error": "Not enough stock", "productID": item.ProductID})
-					return
-				}
-
-				menuItem.Quantity -= item.Quantity
-				if err := tx.Save(&menuItem).Error; err != nil {
-					tx.Rollback()
-					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update menu item stock", "productID": item.ProductID})
-					return
-				}
-
-				itemTotalCost := menuItem.Price.Mul(decimal.NewFromInt(int64(item.Quantity)))
-
-				orderDetail := models.OrderDetail{
-					ItemID:    item.ProductID,
-					Quantity:  item.Quantity,
-					TotalCost: itemTotalCost,
-				}
-				newOrder.OrderDetails = append(newOrder.OrderDetails, orderDetail)
-
-				totalPrice = totalPrice.Add(itemTotalCost)
+			newOrder, err := utils.CreateOrder(tx, userID.(uint), input)
+			if err != nil {
+				tx.Rollback()
+				c.JSON(utils.OrderErrorResponse(err))
+				return
 			}
 
-			newOrder.TotalPrice = totalPrice
-
-			if err := tx.Create(&newOrder).Error; err != nil {
+			if err := utils.SaveIdempotentResponse(tx, c, http.StatusCreated, newOrder); err != nil {
 				tx.Rollback()
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
 				return
 			}
-
-			tx.Commit()
+			if err := tx.Commit().Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+				return
+			}
+			utils.PublishOrderEvent(utils.OrderCreated, newOrder, "")
 			c.JSON(http.StatusCreated, newOrder)
 		})
 	}
 }
 
 // @Summary Get user orders
-// @Description Retrieves all orders placed by the user.
+// @Description Retrieves all orders placed by the user. Item names are in the language chosen by lang or Accept-Language. Nutrition is given per line and per order. Each order has its daily pickup number, the signed pickup code of its QR code and the start of its pickup slot.
 // @Tags orders
 // @Accept json
 // @Produce json
 // @Security ApiKeyAuth
+// @Param lang query string false "kk, ru or en"
 // @Success 200 {array} map[string]interface{} "List of user orders"
 // @Failure 400 {object} map[string]interface{} "error: User ID not found"
 // @Failure 500 {object} map[string]interface{} "error: Failed to retrieve orders"
@@ -111,36 +91,103 @@ func GetOrder(router *gin.Engine) {
 			}
 
 			var userOrders []models.Order
-			if err := initializers.DB.Preload("OrderDetails.MenuItem").Where("user_id = ?", userID.(uint)).Find(&userOrders).Error; err != nil {
+			if err := initializers.DB.Preload("OrderDetails.MenuItem", func(db *gorm.DB) *gorm.DB { return db.Unscoped() }).Preload("OrderDetails.Modifiers").Preload("OrderCombos.Choices").Where("user_id = ?", userID.(uint)).Find(&userOrders).Error; err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders", "details": err.Error()})
 				return
 			}
 
+			itemIDs := []uint{}
+			for _, order := range userOrders {
+				for _, detail := range order.OrderDetails {
+					itemIDs = append(itemIDs, detail.ItemID)
+				}
+				for _, combo := range order.OrderCombos {
+					for _, choice := range combo.Choices {
+						itemIDs = append(itemIDs, choice.ItemID)
+					}
+				}
+			}
+			locale := utils.RequestLocale(c)
+			translations, err := utils.MenuTranslations(initializers.DB, itemIDs, locale)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders", "details": err.Error()})
+				return
+			}
+			c.Header("Content-Language", locale)
+
+			// Combo lines only keep the names of their items, the nutrition comes from the menu.
+			var menuItems []models.Menu
+			if err := initializers.DB.Unscoped().Where("id IN ?", itemIDs).Find(&menuItems).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders", "details": err.Error()})
+				return
+			}
+			nutritionByItem := make(map[uint]models.Nutrition, len(menuItems))
+			for _, item := range menuItems {
+				nutritionByItem[item.ID] = item.Nutrition
+			}
+
 			response := make([]map[string]interface{}, 0)
 			for _, order := range userOrders {
+				var pickupAt interface{}
+				if order.PickupAt != nil {
+					pickupAt = order.PickupAt.Format(time.RFC3339)
+				}
+				var orderNutrition models.Nutrition
 				orderItems := make([]map[string]interface{}, 0)
 				for _, detail := range order.OrderDetails {
+					name, description := utils.TranslatedMenuItem(translations, detail.MenuItem)
+					detailNutrition := detail.MenuItem.Nutrition.Times(detail.Quantity)
+					orderNutrition = orderNutrition.Add(detailNutrition)
 					orderItems = append(orderItems, map[string]interface{}{
 						"id": detail.ID,
 						"item": map[string]interface{}{
 							"ID":          detail.MenuItem.ID,
-							"name":        detail.MenuItem.Name,
-							"description": detail.MenuItem.Description,
-							"price":       detail.MenuItem.Price.String(),
+							"name":        name,
+							"description": description,
+							"price":       detail.UnitPrice.String(),
 						},
+						"modifiers":   detail.ModifierLabels(),
 						"quantity":    detail.Quantity,
 						"total_price": detail.TotalCost.String(),
+						"nutrition":   detailNutrition,
+					})
+				}
+
+				orderCombos := make([]map[string]interface{}, 0, len(order.OrderCombos))
+				for _, combo := range order.OrderCombos {
+					choices := make([]map[string]interface{}, 0, len(combo.Choices))
+					for _, choice := range combo.Choices {
+						orderNutrition = orderNutrition.Add(nutritionByItem[choice.ItemID].Times(combo.Quantity))
+						name, _ := utils.TranslatedMenuItem(translations, models.Menu{ID: choice.ItemID, Name: choice.ItemName})
+						choices = append(choices, map[string]interface{}{
+							"slot":    choice.SlotName,
+							"item_id": choice.ItemID,
+							"name":    name,
+						})
+					}
+					orderCombos = append(orderCombos, map[string]interface{}{
+						"id":          combo.ID,
+						"combo_id":    combo.ComboID,
+						"name":        combo.Name,
+						"choices":     choices,
+						"quantity":    combo.Quantity,
+						"total_price": combo.TotalCost.String(),
 					})
 				}
 
 				response = append(response, map[string]interface{}{
 
-					"order_id":     order.ID,
-					"order_items":  orderItems,
-					"order_status": order.OrderStatus,
-					"order_cost":   order.TotalPrice.String(),
-					"updated_at":   order.UpdatedAt.Format(time.RFC3339Nano),
-					"created_at":   order.CreatedAt.Format(time.RFC3339Nano),
+					"order_id":      order.ID,
+					"order_items":   orderItems,
+					"order_combos":  orderCombos,
+					"order_status":  order.OrderStatus,
+					"pickup_number": order.PickupNumber,
+					"pickup_code":   utils.PickupCode(order),
+					"pickup_at":     pickupAt,
+					"order_cost":    order.TotalPrice.String(),
+					"nutrition":     orderNutrition,
+					"updated_at":    order.UpdatedAt.Format(time.RFC3339Nano),
+					"created_at":    order.CreatedAt.Format(time.RFC3339Nano),
 				})
 			}
 
@@ -150,7 +197,7 @@ func GetOrder(router *gin.Engine) {
 }
 
 // @Summary Update an order status
-// @Description Updates the status of an order, accessible only by admin users.
+// @Description Updates the status of an order, accessible only by admin users. Allowed transitions: preparing -> ready or canceled, ready -> completed or canceled. Canceling a preparing order returns its quantities to the menu stock. The order owner is notified on GET /orders/stream.
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -161,6 +208,7 @@ func GetOrder(router *gin.Engine) {
 // @Failure 400 {object} map[string]interface{} "error: Invalid request or Invalid order status"
 // @Failure 403 {object} map[string]interface{} "error: Only admin can update order status"
 // @Failure 404 {object} map[string]interface{} "error: Order not found"
+// @Failure 409 {object} map[string]interface{} "error: Invalid status transition"
 // @Failure 500 {object} map[string]interface{} "error: Failed to update order status"
 // @Router /orders/{OrderId} [patch]
 func UpdateOrder(router *gin.Engine) {
@@ -184,21 +232,47 @@ func UpdateOrder(router *gin.Engine) {
 
 			switch orderStatus {
 			case models.Canceled, models.Preparing, models.Ready, models.Completed:
+				userID, _ := c.Get("ID")
 				order := &models.Order{}
-				result := initializers.DB.First(order, orderID)
-				if result.Error != nil {
-					c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
-					return
-				}
+				var previousStatus models.Status
+				err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+					if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(order, orderID).Error; err != nil {
+						return errOrderNotFound
+					}
+
+					previousStatus = order.OrderStatus
+					if !models.CanTransition(previousStatus, orderStatus) {
+						return fmt.Errorf("%w: %s -> %s", models.ErrInvalidStatusTransition, previousStatus, orderStatus)
+					}
 
-				order.OrderStatus = orderStatus
+					order.OrderStatus = orderStatus
+					order.StatusChangedBy = userID.(uint)
 
-				// Сохраняем изменения и выполняем проверку перед сохранением
-				if err := initializers.DB.Save(order).Error; err != nil {
+					// Сохраняем изменения и выполняем проверку перед сохранением
+					if err := tx.Save(order).Error; err != nil {
+						return err
+					}
+
+					// Only orders still being prepared give their portions back.
+					if previousStatus == models.Preparing && orderStatus == models.Canceled {
+						return utils.RestockOrder(tx, order.ID)
+					}
+					return nil
+				})
+				if err != nil {
+					if errors.Is(err, errOrderNotFound) {
+						c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+						return
+					}
+					if errors.Is(err, models.ErrInvalidStatusTransition) {
+						c.JSON(http.StatusConflict, gin.H{"error": "Invalid status transition", "details": err.Error()})
+						return
+					}
 					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status", "details": err.Error()})
 					return
 				}
 
+				utils.PublishOrderEvent(utils.OrderStatusChanged, *order, previousStatus)
 				c.JSON(http.StatusOK, gin.H{"message": "Order status updated successfully"})
 			default:
 				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order status"})
@@ -208,7 +282,7 @@ func UpdateOrder(router *gin.Engine) {
 }
 
 // @Summary Delete an order
-// @Description Deletes an order with the specified ID, only if the order status is 'Preparing'.
+// @Description Deletes an order with the specified ID, only if the order status is 'Preparing'. The ordered quantities are returned to the menu stock.
 // @Tags orders
 // @Accept json
 // @Produce json
@@ -231,33 +305,139 @@ func DeleteOrder(router *gin.Engine) {
 			}
 			orderID := c.Param("OrderID")
 			var order models.Order
-			result := initializers.DB.Where("id = ? AND user_id = ?", orderID, userID.(uint)).First(&order)
-			if result.Error != nil {
-				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found or you don't have permission to delete it"})
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				result := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", orderID, userID.(uint)).First(&order)
+				if result.Error != nil {
+					return errOrderNotFound
+				}
+				if order.OrderStatus != models.Preparing {
+					return errOrderNotPreparing
+				}
+				if err := utils.RestockOrder(tx, order.ID); err != nil {
+					return err
+				}
+				// Its lines and status history are deleted with the order by
+				// their foreign keys.
+				return tx.Delete(&order).Error
+			})
+			if err != nil {
+				if errors.Is(err, errOrderNotFound) {
+					c.JSON(http.StatusNotFound, gin.H{"error": "Order not found or you don't have permission to delete it"})
+					return
+				}
+				if errors.Is(err, errOrderNotPreparing) {
+					c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete orders with 'preparing' status"})
+					return
+				}
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete order"})
 				return
 			}
-			if order.OrderStatus != models.Preparing {
-				c.JSON(http.StatusForbidden, gin.H{"error": "You can only delete orders with 'preparing' status"})
+			utils.PublishOrderEvent(utils.OrderDeleted, order, "")
+			c.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
+		})
+	}
+}
+
+// @Summary Get order status history
+// @Description Lists every status change of an order with who made it and when. Available to the order owner and admins.
+// @Tags orders
+// @Produce json
+// @Security ApiKeyAuth
+// @Param OrderId path string true "Order ID"
+// @Success 200 {array} map[string]interface{} "Status changes, oldest first"
+// @Failure 404 {object} map[string]interface{} "error: Order not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve order history"
+// @Router /orders/{OrderId}/history [get]
+func GetOrderHistory(router *gin.Engine) {
+	orders := router.Group("/orders", utils.AuthMiddleware())
+	{
+		orders.GET("/:OrderId/history", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+			role, _ := c.Get("role")
+
+			query := initializers.DB.Where("id = ?", c.Param("OrderId"))
+			if role != "admin" {
+				query = query.Where("user_id = ?", userID.(uint))
+			}
+			var order models.Order
+			if err := query.First(&order).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 				return
 			}
-			if err := initializers.DB.Delete(&order).Error; err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete order"})
+
+			var history []models.OrderStatusHistory
+			if err := initializers.DB.Preload("ChangedBy").Where("order_id = ?", order.ID).Order("created_at, id").Find(&history).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve order history"})
 				return
 			}
-			c.JSON(http.StatusOK, gin.H{"message": "Order deleted successfully"})
+
+			response := make([]map[string]interface{}, 0, len(history))
+			for _, entry := range history {
+				var changedBy map[string]interface{}
+				if entry.ChangedBy != nil {
+					changedBy = map[string]interface{}{
+						"id":       entry.ChangedBy.ID,
+						"username": entry.ChangedBy.Username,
+						"role":     entry.ChangedBy.Role,
+					}
+				}
+				response = append(response, map[string]interface{}{
+					"from_status": entry.FromStatus,
+					"to_status":   entry.ToStatus,
+					"changed_by":  changedBy,
+					"changed_at":  entry.CreatedAt.Format(time.RFC3339Nano),
+				})
+			}
+			c.JSON(http.StatusOK, response)
+		})
+	}
+}
+
+// @Summary Stream order status changes
+// @Description Server-Sent Events stream of the current user's orders. An order.status event is sent whenever one of their orders changes status, with the order id and its old and new status. A ping event is sent every 25 seconds and the stream ends with an expired event when the access token expires. Clients that cannot set the Authorization header pass a ticket from /stream/ticket instead.
+// @Tags orders
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Param ticket query string false "Stream ticket, for clients that cannot set headers"
+// @Success 200 {string} string "Event stream"
+// @Failure 401 {object} map[string]interface{} "error: Unauthorized or Invalid stream ticket"
+// @Router /orders/stream [get]
+func StreamOrderEvents(router *gin.Engine) {
+	orders := router.Group("/orders", utils.StreamAuthMiddleware())
+	{
+		orders.GET("/stream", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			events, unsubscribe := utils.Events.Subscribe(utils.UserTopic(userID.(uint)))
+			defer unsubscribe()
+			utils.StreamEvents(c, events, func(event utils.Event) interface{} { return event.Data })
 		})
 	}
 }
 
+var (
+	errOrderNotFound     = errors.New("order not found")
+	errOrderNotPreparing = errors.New("order is not preparing")
+)
+
 type UpdateOrderData struct {
 	Status string `json:"status" binding:"required"`
 }
 
 type OrderRequest struct {
-	OrderItems []OrderItem `json:"order_items"`
+	OrderItems []OrderItem    `json:"order_items"`
+	Combos     []ComboRequest `json:"combos"`
+	SlotID     uint           `json:"slot_id" example:"3"`
 }
 
 type OrderItem struct {
-	ProductID uint `json:"product_id"`
-	Quantity  int  `json:"quantity"`
+	ProductID uint   `json:"product_id"`
+	Quantity  int    `json:"quantity"`
+	Options   []uint `json:"options"`
+}
+
+type ComboRequest struct {
+	ComboID  uint                `json:"combo_id"`
+	Quantity int                 `json:"quantity"`
+	Choices  []utils.ComboChoice `json:"choices"`
 }
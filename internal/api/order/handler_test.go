@@ -0,0 +1,96 @@
+package order
+
+import (
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"final_project/internal/utils"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func createClient(t *testing.T, db *gorm.DB) (models.User, string) {
+	t.Helper()
+	user := models.User{Username: "client", Email: "client@example.com", Role: models.Client}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	tokens, err := utils.CreateSession(db, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, tokens.AccessToken
+}
+
+func TestDeleteOrderWithModifiers(t *testing.T) {
+	db := testdb.Open(t, "api_order")
+	gin.SetMode(gin.TestMode)
+	user, token := createClient(t, db)
+
+	item := models.Menu{Name: "Plov", Price: decimal.NewFromInt(1500), Quantity: 3, IsAvailable: true}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	group := models.ModifierGroup{
+		ItemID:    item.ID,
+		Name:      "Extras",
+		Kind:      models.Extra,
+		MaxSelect: 2,
+		Options:   []models.ModifierOption{{Name: "Cheese", PriceDelta: decimal.NewFromInt(200)}},
+	}
+	if err := db.Create(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+	order := models.Order{
+		UserID:      user.ID,
+		OrderStatus: models.Preparing,
+		TotalPrice:  decimal.NewFromInt(3400),
+		OrderDetails: []models.OrderDetail{{
+			ItemID:    item.ID,
+			Quantity:  2,
+			UnitPrice: item.Price.Add(decimal.NewFromInt(200)),
+			TotalCost: decimal.NewFromInt(3400),
+			Modifiers: []models.OrderDetailModifier{{
+				OptionID:   group.Options[0].ID,
+				GroupKind:  models.Extra,
+				GroupName:  group.Name,
+				OptionName: "Cheese",
+				PriceDelta: decimal.NewFromInt(200),
+			}},
+		}},
+	}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	DeleteOrder(router)
+	req := httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/orders/%d/", order.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", w.Code, w.Body.String())
+	}
+
+	for _, model := range []interface{}{&models.Order{}, &models.OrderDetail{}, &models.OrderDetailModifier{}, &models.OrderStatusHistory{}} {
+		var count int64
+		if err := db.Model(model).Count(&count).Error; err != nil {
+			t.Fatal(err)
+		}
+		if count != 0 {
+			t.Errorf("%T rows left = %d, want 0", model, count)
+		}
+	}
+	if err := db.First(&item, item.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Quantity != 5 {
+		t.Errorf("stock = %d, want 5 after restock", item.Quantity)
+	}
+}
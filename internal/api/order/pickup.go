@@ -0,0 +1,159 @@
+package order
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultQRSize = 256
+	maxQRSize     = 1024
+)
+
+// @Summary Get the pickup QR code of an order
+// @Description Returns a PNG QR code holding the signed pickup code of the order, to be shown at the counter. Available to the order owner and admins.
+// @Tags orders
+// @Produce png
+// @Security ApiKeyAuth
+// @Param OrderId path string true "Order ID"
+// @Param size query int false "Image size in pixels, at most 1024" default(256)
+// @Success 200 {file} binary "QR code"
+// @Failure 400 {object} map[string]interface{} "error: Invalid size"
+// @Failure 404 {object} map[string]interface{} "error: Order not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to generate QR code"
+// @Router /orders/{OrderId}/qr [get]
+func GetOrderQR(router *gin.Engine) {
+	orders := router.Group("/orders", utils.AuthMiddleware())
+	{
+		orders.GET("/:OrderId/qr", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+			role, _ := c.Get("role")
+
+			size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultQRSize)))
+			if err != nil || size < 64 || size > maxQRSize {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid size"})
+				return
+			}
+
+			query := initializers.DB.Where("id = ?", c.Param("OrderId"))
+			if role != "admin" {
+				query = query.Where("user_id = ?", userID.(uint))
+			}
+			var order models.Order
+			if err := query.First(&order).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				return
+			}
+
+			png, err := qrcode.Encode(utils.PickupCode(order), qrcode.Medium, size)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+				return
+			}
+			c.Header("Cache-Control", "private, max-age=86400")
+			c.Data(http.StatusOK, "image/png", png)
+		})
+	}
+}
+
+// @Summary Hand over an order
+// @Description Checks the pickup code scanned from the customer's QR code and completes the order, accessible only by admin users. Only ready orders can be picked up.
+// @Tags orders
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param pickup body PickupData true "Scanned pickup code"
+// @Success 200 {object} map[string]interface{} "message: Order picked up, order_id, pickup_number, customer"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Invalid pickup code"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Order not found"
+// @Failure 409 {object} map[string]interface{} "error: Order is not ready or Order has already been picked up"
+// @Failure 500 {object} map[string]interface{} "error: Failed to complete order"
+// @Router /orders/pickup [post]
+func PickupOrder(router *gin.Engine) {
+	orders := router.Group("/orders", utils.AuthMiddleware())
+	{
+		orders.POST("/pickup", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var data PickupData
+			if err := c.BindJSON(&data); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			code := strings.TrimSpace(data.Code)
+			orderID, err := utils.CheckPickupCode(code)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup code"})
+				return
+			}
+
+			userID, _ := c.Get("ID")
+			order := &models.Order{}
+			err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Preload("User").First(order, orderID).Error; err != nil {
+					return errOrderNotFound
+				}
+				if utils.PickupCode(*order) != code {
+					return utils.ErrInvalidPickupCode
+				}
+				switch order.OrderStatus {
+				case models.Ready:
+				case models.Completed:
+					return errOrderPickedUp
+				default:
+					return errOrderNotReady
+				}
+
+				order.OrderStatus = models.Completed
+				order.StatusChangedBy = userID.(uint)
+				return tx.Omit("User").Save(order).Error
+			})
+			if err != nil {
+				switch {
+				case errors.Is(err, errOrderNotFound):
+					c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+				case errors.Is(err, utils.ErrInvalidPickupCode):
+					c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pickup code"})
+				case errors.Is(err, errOrderPickedUp):
+					c.JSON(http.StatusConflict, gin.H{"error": "Order has already been picked up"})
+				case errors.Is(err, errOrderNotReady):
+					c.JSON(http.StatusConflict, gin.H{"error": "Order is not ready", "order_status": order.OrderStatus})
+				default:
+					c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete order"})
+				}
+				return
+			}
+
+			utils.PublishOrderEvent(utils.OrderStatusChanged, *order, models.Ready)
+			c.JSON(http.StatusOK, gin.H{
+				"message":       "Order picked up",
+				"order_id":      order.ID,
+				"pickup_number": order.PickupNumber,
+				"customer":      order.User.Username,
+			})
+		})
+	}
+}
+
+var (
+	errOrderNotReady = errors.New("order is not ready")
+	errOrderPickedUp = errors.New("order has already been picked up")
+)
+
+type PickupData struct {
+	Code string `json:"code" binding:"required"`
+}
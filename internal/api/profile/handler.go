@@ -0,0 +1,116 @@
+package profile
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+)
+
+// GetDietaryProfile godoc
+// @Summary Get dietary profile
+// @Description Returns the allergens and diets the current user has declared.
+// @Tags profile
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} DietaryProfile
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve dietary profile"
+// @Router /me/dietary [get]
+func GetDietaryProfile(router *gin.Engine) {
+	profileRoutes := router.Group("/me", utils.AuthMiddleware())
+	{
+		profileRoutes.GET("/dietary", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			var user models.User
+			if err := initializers.DB.Preload("Allergens").Preload("Diets").First(&user, userID.(uint)).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve dietary profile"})
+				return
+			}
+			c.JSON(http.StatusOK, dietaryProfile(user))
+		})
+	}
+}
+
+// UpdateDietaryProfile godoc
+// @Summary Update dietary profile
+// @Description Replaces the allergens and diets of the current user. Omitted lists are left unchanged.
+// @Tags profile
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param profile body DietaryProfile true "Allergen and diet tag names"
+// @Success 200 {object} DietaryProfile
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Unknown tag"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update dietary profile"
+// @Router /me/dietary [put]
+func UpdateDietaryProfile(router *gin.Engine) {
+	profileRoutes := router.Group("/me", utils.AuthMiddleware())
+	{
+		profileRoutes.PUT("/dietary", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			var profile DietaryProfile
+			if err := c.BindJSON(&profile); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var allergens, diets []models.Tag
+			var err error
+			if profile.Allergens != nil {
+				if allergens, err = utils.FindTags(initializers.DB, profile.Allergens, models.Allergen); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
+			if profile.Diets != nil {
+				if diets, err = utils.FindTags(initializers.DB, profile.Diets, models.Dietary); err != nil {
+					c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+					return
+				}
+			}
+
+			var user models.User
+			err = initializers.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.First(&user, userID.(uint)).Error; err != nil {
+					return err
+				}
+				if profile.Allergens != nil {
+					if err := tx.Model(&user).Association("Allergens").Replace(allergens); err != nil {
+						return err
+					}
+				}
+				if profile.Diets != nil {
+					if err := tx.Model(&user).Association("Diets").Replace(diets); err != nil {
+						return err
+					}
+				}
+				return tx.Preload("Allergens").Preload("Diets").First(&user, user.ID).Error
+			})
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update dietary profile"})
+				return
+			}
+			c.JSON(http.StatusOK, dietaryProfile(user))
+		})
+	}
+}
+
+func dietaryProfile(user models.User) DietaryProfile {
+	profile := DietaryProfile{Allergens: []string{}, Diets: []string{}}
+	for _, tag := range user.Allergens {
+		profile.Allergens = append(profile.Allergens, tag.Name)
+	}
+	for _, tag := range user.Diets {
+		profile.Diets = append(profile.Diets, tag.Name)
+	}
+	return profile
+}
+
+type DietaryProfile struct {
+	Allergens []string `json:"allergens"`
+	Diets     []string `json:"diets"`
+}
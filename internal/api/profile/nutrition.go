@@ -0,0 +1,63 @@
+package profile
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+// GetNutritionIntake godoc
+// @Summary Get nutrition intake
+// @Description Sums the calories and macros of the current user's completed orders per day. Both dates default to today.
+// @Tags profile
+// @Produce json
+// @Security ApiKeyAuth
+// @Param from query string false "First day, YYYY-MM-DD"
+// @Param to query string false "Last day, YYYY-MM-DD"
+// @Success 200 {object} map[string]interface{} "from, to, total and days"
+// @Failure 400 {object} map[string]interface{} "error: Invalid date"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve nutrition intake"
+// @Router /me/nutrition [get]
+func GetNutritionIntake(router *gin.Engine) {
+	profileRoutes := router.Group("/me", utils.AuthMiddleware())
+	{
+		profileRoutes.GET("/nutrition", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			now := time.Now()
+			today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+			from, errFrom := parseDay(c.Query("from"), today)
+			to, errTo := parseDay(c.Query("to"), today)
+			if errFrom != nil || errTo != nil || from.After(to) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date"})
+				return
+			}
+
+			days, err := utils.NutritionIntake(initializers.DB, userID.(uint), from, to)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve nutrition intake"})
+				return
+			}
+			var total models.Nutrition
+			for _, day := range days {
+				total = total.Add(day.Nutrition)
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"from":  from.Format("2006-01-02"),
+				"to":    to.Format("2006-01-02"),
+				"total": total,
+				"days":  days,
+			})
+		})
+	}
+}
+
+func parseDay(value string, fallback time.Time) (time.Time, error) {
+	if value == "" {
+		return fallback, nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
@@ -0,0 +1,264 @@
+package review
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetMenuReviews godoc
+// @Summary Get reviews of a menu item
+// @Description Lists the reviews of a menu item, newest first, with its average rating. Hidden reviews are only listed for admin users.
+// @Tags reviews
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "Menu item ID"
+// @Success 200 {object} map[string]interface{} "item_id, rating, rating_count, reviews"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve reviews"
+// @Router /menu/{itemId}/reviews [get]
+func GetMenuReviews(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.GET("/:itemId/reviews", func(c *gin.Context) {
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+
+			query := initializers.DB.Preload("User").Where("item_id = ?", menuItem.ID)
+			if role, _ := c.Get("role"); role != "admin" {
+				query = query.Where("hidden = ?", false)
+			}
+			var reviews []models.Review
+			if err := query.Order("created_at DESC, id DESC").Find(&reviews).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+				return
+			}
+			items := []models.Menu{menuItem}
+			if err := utils.RateMenuItems(initializers.DB, items); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reviews"})
+				return
+			}
+
+			response := make([]map[string]interface{}, 0, len(reviews))
+			for _, review := range reviews {
+				response = append(response, reviewResponse(review))
+			}
+			c.JSON(http.StatusOK, gin.H{
+				"item_id":      menuItem.ID,
+				"rating":       items[0].Rating,
+				"rating_count": items[0].RatingCount,
+				"reviews":      response,
+			})
+		})
+	}
+}
+
+// AddReview godoc
+// @Summary Review a menu item
+// @Description Rates a menu item from 1 to 5 with an optional comment. Only items the user received in a completed order can be reviewed. Reviewing an item again replaces the earlier review.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param itemId path string true "Menu item ID"
+// @Param review body ReviewData true "Rating and comment"
+// @Success 201 {object} map[string]interface{} "message: Review added successfully, review"
+// @Success 200 {object} map[string]interface{} "message: Review updated successfully, review"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request or Rating must be between 1 and 5"
+// @Failure 403 {object} map[string]interface{} "error: You can only review items from your completed orders"
+// @Failure 404 {object} map[string]interface{} "error: Menu item not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to save review"
+// @Router /menu/{itemId}/reviews [post]
+func AddReview(router *gin.Engine) {
+	menuRoutes := router.Group("/menu", utils.AuthMiddleware())
+	{
+		menuRoutes.POST("/:itemId/reviews", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+
+			var data ReviewData
+			if err := c.BindJSON(&data); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			if data.Rating < 1 || data.Rating > 5 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Rating must be between 1 and 5"})
+				return
+			}
+
+			var menuItem models.Menu
+			if err := initializers.DB.First(&menuItem, c.Param("itemId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Menu item not found"})
+				return
+			}
+			received, err := utils.HasReceivedItem(initializers.DB, userID.(uint), menuItem.ID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+				return
+			}
+			if !received {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You can only review items from your completed orders"})
+				return
+			}
+
+			var review models.Review
+			err = initializers.DB.Where("item_id = ? AND user_id = ?", menuItem.ID, userID.(uint)).First(&review).Error
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+				return
+			}
+			created := review.ID == 0
+			review.ItemID = menuItem.ID
+			review.UserID = userID.(uint)
+			review.Rating = data.Rating
+			review.Comment = strings.TrimSpace(data.Comment)
+			if err := initializers.DB.Save(&review).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save review"})
+				return
+			}
+			initializers.DB.First(&review.User, review.UserID)
+
+			if created {
+				c.JSON(http.StatusCreated, gin.H{"message": "Review added successfully", "review": reviewResponse(review)})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Review updated successfully", "review": reviewResponse(review)})
+		})
+	}
+}
+
+// ModerateReview godoc
+// @Summary Moderate a review
+// @Description Hides or shows a review and sets the reply to it, accessible only by admin users. An empty reply removes it.
+// @Tags reviews
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param reviewId path string true "Review ID"
+// @Param moderation body ModerationData true "Hidden flag and reply"
+// @Success 200 {object} map[string]interface{} "message: Review updated successfully, review"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Review not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update review"
+// @Router /reviews/{reviewId} [patch]
+func ModerateReview(router *gin.Engine) {
+	reviewRoutes := router.Group("/reviews", utils.AuthMiddleware())
+	{
+		reviewRoutes.PATCH("/:reviewId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var data ModerationData
+			if err := c.BindJSON(&data); err != nil || (data.Hidden == nil && data.Reply == nil) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var review models.Review
+			if err := initializers.DB.Preload("User").First(&review, c.Param("reviewId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+				return
+			}
+
+			updates := map[string]interface{}{}
+			if data.Hidden != nil {
+				updates["hidden"] = *data.Hidden
+				review.Hidden = *data.Hidden
+			}
+			if data.Reply != nil {
+				review.Reply = strings.TrimSpace(*data.Reply)
+				review.RepliedAt = nil
+				if review.Reply != "" {
+					now := time.Now()
+					review.RepliedAt = &now
+				}
+				updates["reply"] = review.Reply
+				updates["replied_at"] = review.RepliedAt
+			}
+			if err := initializers.DB.Model(&review).Updates(updates).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update review"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Review updated successfully", "review": reviewResponse(review)})
+		})
+	}
+}
+
+// DeleteReview godoc
+// @Summary Delete a review
+// @Description Deletes a review. Users can delete their own reviews, admin users any review.
+// @Tags reviews
+// @Produce json
+// @Security ApiKeyAuth
+// @Param reviewId path string true "Review ID"
+// @Success 200 {object} map[string]interface{} "message: Review deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Review not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete review"
+// @Router /reviews/{reviewId} [delete]
+func DeleteReview(router *gin.Engine) {
+	reviewRoutes := router.Group("/reviews", utils.AuthMiddleware())
+	{
+		reviewRoutes.DELETE("/:reviewId", func(c *gin.Context) {
+			userID, _ := c.Get("ID")
+			role, _ := c.Get("role")
+
+			var review models.Review
+			if err := initializers.DB.First(&review, c.Param("reviewId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+				return
+			}
+			if role != "admin" && review.UserID != userID.(uint) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			if err := initializers.DB.Delete(&review).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete review"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Review deleted successfully"})
+		})
+	}
+}
+
+func reviewResponse(review models.Review) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":         review.ID,
+		"item_id":    review.ItemID,
+		"user_id":    review.UserID,
+		"username":   review.User.Username,
+		"rating":     review.Rating,
+		"comment":    review.Comment,
+		"hidden":     review.Hidden,
+		"created_at": review.CreatedAt,
+		"updated_at": review.UpdatedAt,
+	}
+	if review.Reply != "" {
+		response["reply"] = review.Reply
+		response["replied_at"] = review.RepliedAt
+	}
+	return response
+}
+
+type ReviewData struct {
+	Rating  int    `json:"rating" example:"5"`
+	Comment string `json:"comment"`
+}
+
+type ModerationData struct {
+	Hidden *bool   `json:"hidden"`
+	Reply  *string `json:"reply"`
+}
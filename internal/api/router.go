@@ -2,11 +2,19 @@ package api
 
 import (
 	_ "final_project/docs"
+	"final_project/internal/api/admin"
 	"final_project/internal/api/auth"
 	"final_project/internal/api/basket"
+	"final_project/internal/api/category"
+	"final_project/internal/api/combo"
+	"final_project/internal/api/kitchen"
 	"final_project/internal/api/menu"
 	"final_project/internal/api/order"
+	"final_project/internal/api/profile"
+	"final_project/internal/api/review"
+	"final_project/internal/api/slot"
 	"final_project/internal/api/status"
+	"final_project/internal/api/tag"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -22,22 +30,96 @@ func SetupRouter() *gin.Engine {
 	//auth
 	auth.Login(router)
 	auth.SignUp(router)
+	auth.RefreshToken(router)
+	auth.Logout(router)
+	auth.StreamTicket(router)
+
+	//admin
+	admin.ListUsers(router)
+	admin.GetUser(router)
+	admin.UpdateUserRole(router)
+	admin.UpdateUserStatus(router)
+	admin.DeleteUser(router)
 
 	//basket
 	basket.GetAllBasket(router)
 	basket.DeleteFromBasket(router)
 	basket.AddToBasket(router)
+	basket.UpdateBasketItem(router)
+	basket.RemoveBasketItem(router)
+	basket.UpdateBasketCombo(router)
+	basket.RemoveBasketCombo(router)
+	basket.Checkout(router)
 
 	// menu
 	menu.GetAllMenu(router)
 	menu.AddMenu(router)
 	menu.UpdateMenu(router)
 	menu.DeleteMenu(router)
+	menu.RestoreMenu(router)
+	menu.SetMenuTags(router)
+	menu.UploadMenuImage(router)
+	menu.ServeImages(router)
+	menu.GetTodayMenu(router)
+	menu.GetMenuSchedules(router)
+	menu.AddMenuSchedule(router)
+	menu.DeleteMenuSchedule(router)
+	menu.GetMenuModifiers(router)
+	menu.AddMenuModifierGroup(router)
+	menu.DeleteMenuModifierGroup(router)
+	menu.GetMenuPriceHistory(router)
+	menu.ImportMenu(router)
+	menu.ExportMenu(router)
+	menu.GetMenuTranslations(router)
+	menu.SetMenuTranslation(router)
+	menu.DeleteMenuTranslation(router)
+
+	// tags and dietary profile
+	tag.GetAllTags(router)
+	profile.GetDietaryProfile(router)
+	profile.UpdateDietaryProfile(router)
+	profile.GetNutritionIntake(router)
+
+	// categories
+	category.GetAllCategories(router)
+	category.GetCategoryItems(router)
+	category.AddCategory(router)
+	category.UpdateCategory(router)
+	category.DeleteCategory(router)
+	category.GetCategoryTranslations(router)
+	category.SetCategoryTranslation(router)
+	category.DeleteCategoryTranslation(router)
+
+	// combos
+	combo.GetAllCombos(router)
+	combo.AddCombo(router)
+	combo.UpdateCombo(router)
+	combo.DeleteCombo(router)
+
+	// reviews
+	review.GetMenuReviews(router)
+	review.AddReview(router)
+	review.ModerateReview(router)
+	review.DeleteReview(router)
 
 	order.AddOrder(router)
 	order.GetOrder(router)
 	order.DeleteOrder(router)
 	order.UpdateOrder(router)
+	order.GetOrderHistory(router)
+	order.StreamOrderEvents(router)
+	order.GetOrderQR(router)
+	order.PickupOrder(router)
+
+	// pickup slots
+	slot.GetSlots(router)
+	slot.AddSlots(router)
+	slot.UpdateSlot(router)
+	slot.DeleteSlot(router)
+
+	// kitchen
+	kitchen.GetKitchenQueue(router)
+	kitchen.StreamKitchenOrders(router)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
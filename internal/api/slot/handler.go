@@ -0,0 +1,295 @@
+package slot
+
+import (
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"net/http"
+	"time"
+)
+
+// GetSlots godoc
+// @Summary Get today's pickup slots
+// @Description Lists today's pickup slots with what is booked in them and the remaining capacity. A remaining value is null when the slot has no such limit. Slots that have started, are full or are inactive are not available. Non-admin users only see active slots.
+// @Tags slots
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "date, slots"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve pickup slots"
+// @Router /slots [get]
+func GetSlots(router *gin.Engine) {
+	slotRoutes := router.Group("/slots", utils.AuthMiddleware())
+	{
+		slotRoutes.GET("/", func(c *gin.Context) {
+			query := initializers.DB.Order("start_time, id")
+			if role, _ := c.Get("role"); role != "admin" {
+				query = query.Where("is_active = ?", true)
+			}
+			var slots []models.PickupSlot
+			if err := query.Find(&slots).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pickup slots"})
+				return
+			}
+			now := time.Now()
+			usages, err := utils.SlotUsages(initializers.DB, now)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve pickup slots"})
+				return
+			}
+
+			response := make([]map[string]interface{}, 0, len(slots))
+			for _, slot := range slots {
+				usage := usages[slot.ID]
+				remainingOrders := remaining(slot.MaxOrders, usage.Orders)
+				remainingItems := remaining(slot.MaxItems, usage.Items)
+				full := (remainingOrders != nil && *remainingOrders == 0) || (remainingItems != nil && *remainingItems == 0)
+				response = append(response, map[string]interface{}{
+					"id":               slot.ID,
+					"start":            slot.Start,
+					"end":              slot.End,
+					"pickup_at":        slot.StartOn(now).Format(time.RFC3339),
+					"max_orders":       slot.MaxOrders,
+					"max_items":        slot.MaxItems,
+					"booked_orders":    usage.Orders,
+					"booked_items":     usage.Items,
+					"remaining_orders": remainingOrders,
+					"remaining_items":  remainingItems,
+					"is_active":        slot.IsActive,
+					"available":        slot.IsActive && slot.StartOn(now).After(now) && !full,
+				})
+			}
+			c.JSON(http.StatusOK, gin.H{"date": now.Format("2006-01-02"), "slots": response})
+		})
+	}
+}
+
+// AddSlots godoc
+// @Summary Add pickup slots
+// @Description Adds daily pickup slots, accessible only by admin users. With length_minutes the time from start to end is split into slots of that length, e.g. 10-minute windows; otherwise one slot is added. Slots may not overlap.
+// @Tags slots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param slots body SlotData true "Slot times and limits"
+// @Success 201 {array} models.PickupSlot "Created slots"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 409 {object} map[string]interface{} "error: Slot overlaps an existing slot"
+// @Failure 500 {object} map[string]interface{} "error: Failed to add pickup slots"
+// @Router /slots [post]
+func AddSlots(router *gin.Engine) {
+	slotRoutes := router.Group("/slots", utils.AuthMiddleware())
+	{
+		slotRoutes.POST("/", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var data SlotData
+			if err := c.BindJSON(&data); err != nil || data.LengthMinutes < 0 || data.MaxOrders < 0 || data.MaxItems < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+			start, errStart := time.Parse("15:04", data.Start)
+			end, errEnd := time.Parse("15:04", data.End)
+			if errStart != nil || errEnd != nil || !end.After(start) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": "start and end must be HH:MM with start before end"})
+				return
+			}
+
+			length := end.Sub(start)
+			if data.LengthMinutes > 0 {
+				length = time.Duration(data.LengthMinutes) * time.Minute
+			}
+			slots := []models.PickupSlot{}
+			for from := start; !from.Add(length).After(end); from = from.Add(length) {
+				slots = append(slots, models.PickupSlot{
+					Start:     from.Format("15:04"),
+					End:       from.Add(length).Format("15:04"),
+					MaxOrders: data.MaxOrders,
+					MaxItems:  data.MaxItems,
+					IsActive:  data.IsActive == nil || *data.IsActive,
+				})
+			}
+			if len(slots) == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "details": "length_minutes is longer than the time from start to end"})
+				return
+			}
+
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				// Two admins adding overlapping slots at the same time would
+				// both find no overlap. The lock lets one add slots at a time
+				// while orders can still lock their slots.
+				if err := tx.Exec("LOCK TABLE pickup_slots IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+					return err
+				}
+				var overlapping int64
+				if err := tx.Model(&models.PickupSlot{}).Where("start_time < ? AND end_time > ?", slots[0].Start, slots[len(slots)-1].End).Count(&overlapping).Error; err != nil {
+					return err
+				}
+				if overlapping > 0 {
+					return errSlotOverlaps
+				}
+				return tx.Create(&slots).Error
+			})
+			if errors.Is(err, errSlotOverlaps) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Slot overlaps an existing slot"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add pickup slots"})
+				return
+			}
+			c.JSON(http.StatusCreated, slots)
+		})
+	}
+}
+
+// UpdateSlot godoc
+// @Summary Update a pickup slot
+// @Description Changes the limits of a pickup slot or turns it on or off, accessible only by admin users. Orders already booked in the slot are kept.
+// @Tags slots
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param slotId path string true "Slot ID"
+// @Param updates body UpdateSlotData true "Fields to update"
+// @Success 200 {object} models.PickupSlot "Updated slot"
+// @Failure 400 {object} map[string]interface{} "error: Invalid request"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Slot not found"
+// @Failure 500 {object} map[string]interface{} "error: Failed to update pickup slot"
+// @Router /slots/{slotId} [patch]
+func UpdateSlot(router *gin.Engine) {
+	slotRoutes := router.Group("/slots", utils.AuthMiddleware())
+	{
+		slotRoutes.PATCH("/:slotId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var data UpdateSlotData
+			if err := c.BindJSON(&data); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			var slot models.PickupSlot
+			if err := initializers.DB.First(&slot, c.Param("slotId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Slot not found"})
+				return
+			}
+			if data.MaxOrders != nil {
+				slot.MaxOrders = *data.MaxOrders
+			}
+			if data.MaxItems != nil {
+				slot.MaxItems = *data.MaxItems
+			}
+			if data.IsActive != nil {
+				slot.IsActive = *data.IsActive
+			}
+			if slot.MaxOrders < 0 || slot.MaxItems < 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+				return
+			}
+
+			if err := initializers.DB.Save(&slot).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pickup slot"})
+				return
+			}
+			c.JSON(http.StatusOK, slot)
+		})
+	}
+}
+
+// DeleteSlot godoc
+// @Summary Delete a pickup slot
+// @Description Deletes a pickup slot, accessible only by admin users. Slots that orders were booked in cannot be deleted and should be deactivated instead.
+// @Tags slots
+// @Produce json
+// @Security ApiKeyAuth
+// @Param slotId path string true "Slot ID"
+// @Success 200 {object} map[string]interface{} "message: Slot deleted successfully"
+// @Failure 403 {object} map[string]interface{} "error: Insufficient permissions"
+// @Failure 404 {object} map[string]interface{} "error: Slot not found"
+// @Failure 409 {object} map[string]interface{} "error: Slot has orders, deactivate it instead"
+// @Failure 500 {object} map[string]interface{} "error: Failed to delete pickup slot"
+// @Router /slots/{slotId} [delete]
+func DeleteSlot(router *gin.Engine) {
+	slotRoutes := router.Group("/slots", utils.AuthMiddleware())
+	{
+		slotRoutes.DELETE("/:slotId", func(c *gin.Context) {
+			role, _ := c.Get("role")
+			if role != "admin" {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				return
+			}
+
+			var slot models.PickupSlot
+			if err := initializers.DB.First(&slot, c.Param("slotId")).Error; err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Slot not found"})
+				return
+			}
+
+			err := initializers.DB.Transaction(func(tx *gorm.DB) error {
+				var orderCount int64
+				if err := tx.Model(&models.Order{}).Where("pickup_slot_id = ?", slot.ID).Count(&orderCount).Error; err != nil {
+					return err
+				}
+				if orderCount > 0 {
+					return errSlotHasOrders
+				}
+				return tx.Delete(&slot).Error
+			})
+			if errors.Is(err, errSlotHasOrders) {
+				c.JSON(http.StatusConflict, gin.H{"error": "Slot has orders, deactivate it instead"})
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pickup slot"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"message": "Slot deleted successfully"})
+		})
+	}
+}
+
+// remaining returns how much of limit is left after used, or nil when there
+// is no limit.
+func remaining(limit, used int) *int {
+	if limit == 0 {
+		return nil
+	}
+	left := limit - used
+	if left < 0 {
+		left = 0
+	}
+	return &left
+}
+
+var (
+	errSlotHasOrders = errors.New("slot has orders")
+	errSlotOverlaps  = errors.New("slot overlaps an existing slot")
+)
+
+type SlotData struct {
+	Start         string `json:"start" example:"11:30"`
+	End           string `json:"end" example:"14:00"`
+	LengthMinutes int    `json:"length_minutes" example:"10"`
+	MaxOrders     int    `json:"max_orders" example:"30"`
+	MaxItems      int    `json:"max_items" example:"80"`
+	IsActive      *bool  `json:"is_active"`
+}
+
+type UpdateSlotData struct {
+	MaxOrders *int  `json:"max_orders"`
+	MaxItems  *int  `json:"max_items"`
+	IsActive  *bool `json:"is_active"`
+}
@@ -0,0 +1,38 @@
+package tag
+
+import (
+	"final_project/initializers"
+	"final_project/internal/models"
+	"final_project/internal/utils"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GetAllTags godoc
+// @Summary Get allergen and dietary tags
+// @Description Lists the tags that can be put on menu items and declared in the user's dietary profile.
+// @Tags tags
+// @Produce json
+// @Security ApiKeyAuth
+// @Param kind query string false "allergen or dietary"
+// @Success 200 {object} map[string]interface{} "tags: list of tags"
+// @Failure 500 {object} map[string]interface{} "error: Failed to retrieve tags"
+// @Router /tags [get]
+func GetAllTags(router *gin.Engine) {
+	tagRoutes := router.Group("/tags", utils.AuthMiddleware())
+	{
+		tagRoutes.GET("/", func(c *gin.Context) {
+			query := initializers.DB.Order("kind, name")
+			if kind := c.Query("kind"); kind != "" {
+				query = query.Where("kind = ?", kind)
+			}
+
+			var tags []models.Tag
+			if err := query.Find(&tags).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+				return
+			}
+			c.JSON(http.StatusOK, gin.H{"tags": tags})
+		})
+	}
+}
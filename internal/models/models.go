@@ -2,8 +2,10 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"math"
 	"time"
 )
 
@@ -14,6 +16,69 @@ const (
 	Client Role = "client"
 )
 
+type TagKind string
+
+const (
+	Allergen TagKind = "allergen"
+	Dietary  TagKind = "dietary"
+)
+
+// DefaultTags are created on startup so menu items and users can refer to them by name.
+var DefaultTags = []Tag{
+	{Name: "nuts", Kind: Allergen},
+	{Name: "peanuts", Kind: Allergen},
+	{Name: "gluten", Kind: Allergen},
+	{Name: "lactose", Kind: Allergen},
+	{Name: "eggs", Kind: Allergen},
+	{Name: "soy", Kind: Allergen},
+	{Name: "fish", Kind: Allergen},
+	{Name: "shellfish", Kind: Allergen},
+	{Name: "sesame", Kind: Allergen},
+	{Name: "vegan", Kind: Dietary},
+	{Name: "vegetarian", Kind: Dietary},
+	{Name: "halal", Kind: Dietary},
+	{Name: "gluten-free", Kind: Dietary},
+}
+
+type ModifierKind string
+
+const (
+	// Variant groups pick exactly one option, such as the portion size.
+	Variant ModifierKind = "variant"
+	Extra   ModifierKind = "extra"
+	Removal ModifierKind = "removal"
+)
+
+type MealPeriod string
+
+const (
+	AllDay    MealPeriod = ""
+	Breakfast MealPeriod = "breakfast"
+	Lunch     MealPeriod = "lunch"
+	Dinner    MealPeriod = "dinner"
+)
+
+// mealPeriodHours holds the [start, end) hour of each meal period.
+var mealPeriodHours = map[MealPeriod][2]int{
+	Breakfast: {7, 11},
+	Lunch:     {11, 16},
+	Dinner:    {16, 21},
+}
+
+// MealPeriodsAt returns the meal periods the given time falls into.
+func MealPeriodsAt(t time.Time) []MealPeriod {
+	periods := []MealPeriod{}
+	for period, hours := range mealPeriodHours {
+		if t.Hour() >= hours[0] && t.Hour() < hours[1] {
+			periods = append(periods, period)
+		}
+	}
+	return periods
+}
+
+// Locales are the languages menu content can be translated to.
+var Locales = []string{"kk", "ru", "en"}
+
 type Status string
 
 const (
@@ -29,36 +94,114 @@ type User struct {
 	Email    string `gorm:"unique" validate:"email"`
 	Password string
 	Role     Role
+	Disabled bool     `gorm:"default:false"`
 	Orders   []Order  `gorm:"foreignKey:UserID"`
-	Baskets  []Basket `gorm:"foreignKey:UserID"`
+	Baskets  []Basket `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	// Allergens the user must avoid and diets they follow.
+	Allergens []Tag `gorm:"many2many:user_allergens;constraint:OnDelete:CASCADE" json:",omitempty"`
+	Diets     []Tag `gorm:"many2many:user_diets;constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+type Session struct {
+	ID         uint   `gorm:"primaryKey"`
+	UserID     uint   `gorm:"index"`
+	RefreshJTI string `gorm:"type:varchar(64)"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+	User       User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
 }
 type Order struct {
-	ID           uint `gorm:"primaryKey"`
-	UserID       uint
-	OrderStatus  Status `gorm:"type:varchar(255)"`
+	ID          uint `gorm:"primaryKey"`
+	UserID      uint
+	OrderStatus Status `gorm:"type:varchar(255)"`
+	// PickupNumber is called out at the counter. Numbering restarts every
+	// PickupDate.
+	PickupNumber int       `gorm:"uniqueIndex:idx_order_pickup"`
+	PickupDate   time.Time `gorm:"type:date;uniqueIndex:idx_order_pickup"`
+	// PickupAt is the start of the pickup slot the order was booked in.
+	PickupSlotID *uint `gorm:"index"`
+	PickupAt     *time.Time
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	TotalPrice   decimal.Decimal
-	User         User          `gorm:"foreignKey:UserID"`
-	OrderDetails []OrderDetail `gorm:"foreignKey:OrderID"`
+	User         User                 `gorm:"foreignKey:UserID"`
+	PickupSlot   *PickupSlot          `gorm:"foreignKey:PickupSlotID" json:",omitempty"`
+	OrderDetails []OrderDetail        `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE"`
+	OrderCombos  []OrderCombo         `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:",omitempty"`
+	History      []OrderStatusHistory `gorm:"foreignKey:OrderID;constraint:OnDelete:CASCADE" json:",omitempty"`
+	// Warnings are shown to the user when the order is placed and are not stored.
+	Warnings []string `gorm:"-" json:",omitempty"`
+	// StatusChangedBy is the user recorded in the status history when the order
+	// is saved. When it is not set, the change is recorded without a user.
+	StatusChangedBy uint `gorm:"-" json:"-"`
+	previousStatus  Status
+}
+type OrderStatusHistory struct {
+	ID          uint   `gorm:"primaryKey"`
+	OrderID     uint   `gorm:"index"`
+	FromStatus  Status `gorm:"type:varchar(255)"`
+	ToStatus    Status `gorm:"type:varchar(255)"`
+	ChangedByID *uint
+	CreatedAt   time.Time
+	Order       Order `gorm:"foreignKey:OrderID"`
+	ChangedBy   *User `gorm:"foreignKey:ChangedByID;constraint:OnDelete:SET NULL"`
 }
 type OrderDetail struct {
-	ID        uint `gorm:"primaryKey;autoIncrement"`
-	OrderID   uint
-	ItemID    uint
+	ID       uint `gorm:"primaryKey;autoIncrement"`
+	OrderID  uint
+	ItemID   uint
+	Quantity int
+	// UnitPrice is what one item cost when the order was placed, the chosen
+	// modifiers included.
+	UnitPrice decimal.Decimal
+	TotalCost decimal.Decimal
+	Order     Order                 `gorm:"foreignKey:OrderID"`
+	MenuItem  Menu                  `gorm:"foreignKey:ItemID"`
+	Modifiers []OrderDetailModifier `gorm:"foreignKey:OrderDetailID;constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+
+// OrderDetailModifier is a copy of a chosen modifier option taken when the
+// order was placed, so later menu changes do not alter the order.
+type OrderDetailModifier struct {
+	ID            uint `gorm:"primaryKey"`
+	OrderDetailID uint `gorm:"index"`
+	OptionID      uint
+	GroupKind     ModifierKind `gorm:"type:varchar(32)"`
+	GroupName     string
+	OptionName    string
+	PriceDelta    decimal.Decimal
+}
+
+// OrderCombo is a combo line of an order. Its choices are copied so the
+// order keeps what was served even if the combo changes later.
+type OrderCombo struct {
+	ID        uint `gorm:"primaryKey"`
+	OrderID   uint `gorm:"index"`
+	ComboID   uint
+	Name      string
 	Quantity  int
 	TotalCost decimal.Decimal
-	Order     Order `gorm:"foreignKey:OrderID"`
-	MenuItem  Menu  `gorm:"foreignKey:ItemID"`
+	Choices   []OrderComboChoice `gorm:"foreignKey:OrderComboID;constraint:OnDelete:CASCADE"`
+	Combo     Combo              `gorm:"foreignKey:ComboID" json:"-"`
+}
+type OrderComboChoice struct {
+	ID           uint `gorm:"primaryKey"`
+	OrderComboID uint `gorm:"index"`
+	SlotID       uint
+	SlotName     string
+	ItemID       uint
+	ItemName     string
 }
 type Basket struct {
-	ID          uint `gorm:"primaryKey"`
-	UserID      uint
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	TotalPrice  decimal.Decimal
-	User        User         `gorm:"foreignKey:UserID"`
-	BasketItems []BasketItem `gorm:"foreignKey:BasketID"`
+	ID           uint `gorm:"primaryKey"`
+	UserID       uint
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	TotalPrice   decimal.Decimal
+	User         User          `gorm:"foreignKey:UserID"`
+	BasketItems  []BasketItem  `gorm:"foreignKey:BasketID;constraint:OnDelete:CASCADE"`
+	BasketCombos []BasketCombo `gorm:"foreignKey:BasketID;constraint:OnDelete:CASCADE"`
 }
 
 type BasketItem struct {
@@ -66,19 +209,230 @@ type BasketItem struct {
 	BasketID uint
 	ItemID   uint
 	Quantity int
+	// UnitPrice is the menu price of the item when it was added, so the
+	// customer can be told about price changes before checkout.
+	UnitPrice decimal.Decimal
+	Price     decimal.Decimal
+	// OptionsKey is the sorted list of chosen option IDs; lines with the same
+	// item and key are merged.
+	OptionsKey string           `gorm:"type:varchar(255)"`
+	Basket     Basket           `gorm:"foreignKey:BasketID"`
+	MenuItem   Menu             `gorm:"foreignKey:ItemID"`
+	Options    []ModifierOption `gorm:"many2many:basket_item_options;constraint:OnDelete:CASCADE" json:",omitempty"`
+}
+type BasketCombo struct {
+	ID       uint `gorm:"primaryKey"`
+	BasketID uint `gorm:"index"`
+	ComboID  uint
+	Quantity int
 	Price    decimal.Decimal
-	Basket   Basket `gorm:"foreignKey:BasketID"`
-	MenuItem Menu   `gorm:"foreignKey:ItemID"`
+	// ChoicesKey is the sorted list of slot:item choices; lines with the same
+	// combo and key are merged.
+	ChoicesKey string              `gorm:"type:varchar(255)"`
+	Combo      Combo               `gorm:"foreignKey:ComboID;constraint:OnDelete:CASCADE"`
+	Choices    []BasketComboChoice `gorm:"foreignKey:BasketComboID;constraint:OnDelete:CASCADE"`
+}
+type BasketComboChoice struct {
+	ID            uint `gorm:"primaryKey"`
+	BasketComboID uint `gorm:"index"`
+	SlotID        uint
+	ItemID        uint
+	Slot          ComboSlot `gorm:"foreignKey:SlotID" json:"-"`
+	MenuItem      Menu      `gorm:"foreignKey:ItemID" json:"-"`
+}
+
+// Combo is a set meal sold at its own price, such as a business lunch of
+// soup, main and drink. Each slot is filled with one of its menu items.
+type Combo struct {
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	Description string
+	Price       decimal.Decimal
+	IsAvailable bool
+	Slots       []ComboSlot `gorm:"foreignKey:ComboID;constraint:OnDelete:CASCADE"`
+}
+type ComboSlot struct {
+	ID        uint `gorm:"primaryKey"`
+	ComboID   uint `gorm:"index"`
+	Name      string
+	SortOrder int
+	Items     []Menu `gorm:"many2many:combo_slot_items;constraint:OnDelete:CASCADE"`
 }
 type Menu struct {
-	ID           uint `gorm:"primaryKey"`
-	Name         string
-	Description  string
-	Price        decimal.Decimal
-	Quantity     int
-	IsAvailable  bool
-	OrderDetails []OrderDetail `gorm:"foreignKey:ItemID"`
-	BasketItems  []BasketItem  `gorm:"foreignKey:ItemID"`
+	ID          uint `gorm:"primaryKey"`
+	Name        string
+	Description string
+	Price       decimal.Decimal
+	Quantity    int
+	IsAvailable bool
+	// DeletedAt marks an archived item. It is hidden from the menu but kept
+	// for the orders that refer to it.
+	DeletedAt    gorm.DeletedAt `gorm:"index"`
+	ImageURL     string         `gorm:"type:varchar(255)"`
+	ThumbnailURL string         `gorm:"type:varchar(255)"`
+	Nutrition    Nutrition      `gorm:"embedded"`
+	// Rating is the average of the visible reviews and RatingCount their
+	// number. Both are filled in when listing the menu.
+	Rating         float64         `gorm:"-"`
+	RatingCount    int64           `gorm:"-"`
+	CategoryID     *uint           `gorm:"index"`
+	Category       *Category       `gorm:"foreignKey:CategoryID" json:",omitempty"`
+	Tags           []Tag           `gorm:"many2many:menu_tags;" json:",omitempty"`
+	Schedules      []MenuSchedule  `gorm:"foreignKey:ItemID" json:",omitempty"`
+	ModifierGroups []ModifierGroup `gorm:"foreignKey:ItemID" json:",omitempty"`
+	OrderDetails   []OrderDetail   `gorm:"foreignKey:ItemID" json:",omitempty"`
+	BasketItems    []BasketItem    `gorm:"foreignKey:ItemID" json:",omitempty"`
+}
+
+// Nutrition is the energy and macros of one portion, or of several when summed.
+type Nutrition struct {
+	Kcal         float64 `gorm:"default:0" json:"kcal"`
+	Protein      float64 `gorm:"default:0" json:"protein"`
+	Fat          float64 `gorm:"default:0" json:"fat"`
+	Carbs        float64 `gorm:"default:0" json:"carbs"`
+	PortionGrams int     `gorm:"default:0" json:"portion_grams"`
+}
+
+// ModifierGroup is a set of options for a menu item, e.g. portion sizes or
+// extras. Customers pick between MinSelect and MaxSelect of its options.
+type ModifierGroup struct {
+	ID        uint `gorm:"primaryKey"`
+	ItemID    uint `gorm:"index"`
+	Name      string
+	Kind      ModifierKind `gorm:"type:varchar(32)"`
+	MinSelect int
+	MaxSelect int
+	SortOrder int
+	Options   []ModifierOption `gorm:"foreignKey:GroupID;constraint:OnDelete:CASCADE"`
+	MenuItem  Menu             `gorm:"foreignKey:ItemID" json:"-"`
+}
+type ModifierOption struct {
+	ID         uint `gorm:"primaryKey"`
+	GroupID    uint `gorm:"index"`
+	Name       string
+	PriceDelta decimal.Decimal
+	Group      *ModifierGroup `gorm:"foreignKey:GroupID" json:",omitempty"`
+}
+type MenuSchedule struct {
+	ID     uint `gorm:"primaryKey"`
+	ItemID uint `gorm:"index"`
+	// Weekdays is a bit set of 1 << time.Weekday; zero means every day.
+	Weekdays   int
+	StartDate  *time.Time `gorm:"type:date"`
+	EndDate    *time.Time `gorm:"type:date"`
+	MealPeriod MealPeriod `gorm:"type:varchar(32)"`
+	MenuItem   Menu       `gorm:"foreignKey:ItemID" json:"-"`
+}
+type MenuPriceHistory struct {
+	ID          uint `gorm:"primaryKey"`
+	ItemID      uint `gorm:"index"`
+	OldPrice    decimal.Decimal
+	NewPrice    decimal.Decimal
+	ChangedByID *uint
+	CreatedAt   time.Time
+	MenuItem    Menu  `gorm:"foreignKey:ItemID" json:"-"`
+	ChangedBy   *User `gorm:"foreignKey:ChangedByID;constraint:OnDelete:SET NULL" json:",omitempty"`
+}
+
+// MenuTranslation holds the name and description of a menu item in one locale.
+type MenuTranslation struct {
+	ID          uint   `gorm:"primaryKey"`
+	ItemID      uint   `gorm:"uniqueIndex:idx_menu_translation"`
+	Locale      string `gorm:"type:varchar(8);uniqueIndex:idx_menu_translation"`
+	Name        string
+	Description string
+	MenuItem    Menu `gorm:"foreignKey:ItemID" json:"-"`
+}
+type CategoryTranslation struct {
+	ID         uint   `gorm:"primaryKey"`
+	CategoryID uint   `gorm:"uniqueIndex:idx_category_translation"`
+	Locale     string `gorm:"type:varchar(8);uniqueIndex:idx_category_translation"`
+	Name       string
+	Category   Category `gorm:"foreignKey:CategoryID" json:"-"`
+}
+
+// Review is a user's rating of a menu item they received in a completed
+// order. Each user has at most one review per item. Hidden reviews are left
+// out of listings and the average rating.
+type Review struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	ItemID    uint       `gorm:"uniqueIndex:idx_review_item_user" json:"item_id"`
+	UserID    uint       `gorm:"uniqueIndex:idx_review_item_user" json:"user_id"`
+	Rating    int        `json:"rating"`
+	Comment   string     `json:"comment"`
+	Hidden    bool       `gorm:"default:false" json:"hidden"`
+	Reply     string     `json:"reply,omitempty"`
+	RepliedAt *time.Time `json:"replied_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	MenuItem  Menu       `gorm:"foreignKey:ItemID" json:"-"`
+	User      User       `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// IdempotencyKey remembers the response to a request sent with an
+// Idempotency-Key header so that retries get the same response. StatusCode
+// is zero while the first request is still being handled.
+type IdempotencyKey struct {
+	ID          uint   `gorm:"primaryKey"`
+	UserID      uint   `gorm:"uniqueIndex:idx_idempotency_key"`
+	Key         string `gorm:"type:varchar(255);uniqueIndex:idx_idempotency_key"`
+	RequestHash string `gorm:"type:varchar(64)"`
+	StatusCode  int
+	ContentType string
+	Response    []byte
+	CreatedAt   time.Time `gorm:"index"`
+	User        User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// PickupCounter is the last pickup number given out on a day.
+type PickupCounter struct {
+	Day    time.Time `gorm:"primaryKey;type:date"`
+	Number int
+}
+
+// PickupSlot is a daily pickup window, e.g. 12:50 to 13:00. Each day at most
+// MaxOrders orders holding at most MaxItems portions can be booked in it;
+// zero means no limit.
+type PickupSlot struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	Start     string `gorm:"column:start_time;type:varchar(5)" json:"start" example:"12:50"`
+	End       string `gorm:"column:end_time;type:varchar(5)" json:"end" example:"13:00"`
+	MaxOrders int    `json:"max_orders"`
+	MaxItems  int    `json:"max_items"`
+	IsActive  bool   `gorm:"default:true" json:"is_active"`
+}
+type Tag struct {
+	ID   uint    `gorm:"primaryKey"`
+	Name string  `gorm:"unique"`
+	Kind TagKind `gorm:"type:varchar(32)"`
+}
+type Category struct {
+	ID        uint   `gorm:"primaryKey"`
+	Name      string `gorm:"unique"`
+	SortOrder int
+	Items     []Menu `gorm:"foreignKey:CategoryID" json:",omitempty"`
+}
+
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
+
+var ErrInvalidStatusTransition = errors.New("invalid order status transition")
+
+// orderTransitions lists the statuses each status may move to.
+// Canceled and Completed are final.
+var orderTransitions = map[Status][]Status{
+	Preparing: {Ready, Canceled},
+	Ready:     {Completed, Canceled},
+}
+
+func CanTransition(from, to Status) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
 }
 
 func (o *Order) BeforeSave(tx *gorm.DB) (err error) {
@@ -90,6 +444,200 @@ func (o *Order) BeforeSave(tx *gorm.DB) (err error) {
 	}
 }
 
+func (o *Order) BeforeCreate(tx *gorm.DB) (err error) {
+	if o.OrderStatus != Preparing {
+		return fmt.Errorf("%w: new orders must be %s", ErrInvalidStatusTransition, Preparing)
+	}
+
+	now := time.Now()
+	o.PickupDate = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	// The number is taken on a connection of its own rather than in the
+	// order's transaction, so the counter row is only locked for this
+	// statement and orders placed at the same time do not wait for each
+	// other. An order that is rolled back leaves a gap in the numbers.
+	row := tx.Config.ConnPool.QueryRowContext(tx.Statement.Context, `INSERT INTO pickup_counters (day, number) VALUES ($1, 1)
+		ON CONFLICT (day) DO UPDATE SET number = pickup_counters.number + 1
+		RETURNING number`, o.PickupDate)
+	return row.Scan(&o.PickupNumber)
+}
+
+func (o *Order) BeforeUpdate(tx *gorm.DB) (err error) {
+	var current Order
+	if err := tx.Session(&gorm.Session{NewDB: true}).Select("order_status").First(&current, o.ID).Error; err != nil {
+		return err
+	}
+	o.previousStatus = current.OrderStatus
+	if current.OrderStatus == o.OrderStatus {
+		return nil
+	}
+	if !CanTransition(current.OrderStatus, o.OrderStatus) {
+		return fmt.Errorf("%w: %s -> %s", ErrInvalidStatusTransition, current.OrderStatus, o.OrderStatus)
+	}
+	return nil
+}
+
+func (o *Order) AfterSave(tx *gorm.DB) (err error) {
+	if o.previousStatus == o.OrderStatus {
+		return nil
+	}
+	history := OrderStatusHistory{
+		OrderID:    o.ID,
+		FromStatus: o.previousStatus,
+		ToStatus:   o.OrderStatus,
+	}
+	if o.StatusChangedBy != 0 {
+		changedBy := o.StatusChangedBy
+		history.ChangedByID = &changedBy
+	}
+	if err := tx.Session(&gorm.Session{NewDB: true}).Create(&history).Error; err != nil {
+		return err
+	}
+	o.previousStatus = o.OrderStatus
+	return nil
+}
+
+func (g *ModifierGroup) BeforeSave(tx *gorm.DB) (err error) {
+	switch g.Kind {
+	case Variant:
+		if g.MinSelect != 1 || g.MaxSelect != 1 {
+			return errors.New("variant groups must select exactly one option")
+		}
+	case Extra, Removal:
+	default:
+		return errors.New("invalid modifier kind")
+	}
+	if g.MinSelect < 0 || g.MaxSelect < 1 || g.MaxSelect < g.MinSelect {
+		return errors.New("invalid modifier selection limits")
+	}
+	return nil
+}
+
+// ModifierLabel is how the kitchen sees a chosen option, e.g. "large",
+// "+cheese" or "no onions".
+func ModifierLabel(kind ModifierKind, name string) string {
+	switch kind {
+	case Extra:
+		return "+" + name
+	case Removal:
+		return "no " + name
+	default:
+		return name
+	}
+}
+
+func (m OrderDetailModifier) Label() string {
+	return ModifierLabel(m.GroupKind, m.OptionName)
+}
+
+func (d OrderDetail) ModifierLabels() []string {
+	labels := make([]string, 0, len(d.Modifiers))
+	for _, modifier := range d.Modifiers {
+		labels = append(labels, modifier.Label())
+	}
+	return labels
+}
+
+// Times returns the nutrition of quantity portions.
+func (n Nutrition) Times(quantity int) Nutrition {
+	q := float64(quantity)
+	return Nutrition{
+		Kcal:         roundNutrient(n.Kcal * q),
+		Protein:      roundNutrient(n.Protein * q),
+		Fat:          roundNutrient(n.Fat * q),
+		Carbs:        roundNutrient(n.Carbs * q),
+		PortionGrams: n.PortionGrams * quantity,
+	}
+}
+
+func (n Nutrition) Add(other Nutrition) Nutrition {
+	return Nutrition{
+		Kcal:         roundNutrient(n.Kcal + other.Kcal),
+		Protein:      roundNutrient(n.Protein + other.Protein),
+		Fat:          roundNutrient(n.Fat + other.Fat),
+		Carbs:        roundNutrient(n.Carbs + other.Carbs),
+		PortionGrams: n.PortionGrams + other.PortionGrams,
+	}
+}
+
+var ErrInvalidNutrition = errors.New("nutrition values must not be negative")
+
+func (n Nutrition) Validate() error {
+	if n.Kcal < 0 || n.Protein < 0 || n.Fat < 0 || n.Carbs < 0 || n.PortionGrams < 0 {
+		return ErrInvalidNutrition
+	}
+	return nil
+}
+
+// roundNutrient keeps one decimal so sums do not show float noise.
+func roundNutrient(value float64) float64 {
+	return math.Round(value*10) / 10
+}
+
+func (m *Menu) BeforeSave(tx *gorm.DB) (err error) {
+	return m.Nutrition.Validate()
+}
+
+var ErrInvalidRating = errors.New("rating must be between 1 and 5")
+
+func (r *Review) BeforeSave(tx *gorm.DB) (err error) {
+	if r.Rating < 1 || r.Rating > 5 {
+		return ErrInvalidRating
+	}
+	return nil
+}
+
+func (m *MenuSchedule) BeforeSave(tx *gorm.DB) (err error) {
+	if _, ok := mealPeriodHours[m.MealPeriod]; !ok && m.MealPeriod != AllDay {
+		return errors.New("invalid meal period")
+	}
+	if m.StartDate != nil && m.EndDate != nil && m.EndDate.Before(*m.StartDate) {
+		return errors.New("schedule ends before it starts")
+	}
+	return nil
+}
+
+// StartOn returns when the slot starts on the day.
+func (s PickupSlot) StartOn(day time.Time) time.Time {
+	return clockOn(day, s.Start)
+}
+
+// EndOn returns when the slot ends on the day.
+func (s PickupSlot) EndOn(day time.Time) time.Time {
+	return clockOn(day, s.End)
+}
+
+// clockOn returns the HH:MM time of day on the day.
+func clockOn(day time.Time, clock string) time.Time {
+	at, _ := time.Parse("15:04", clock)
+	return time.Date(day.Year(), day.Month(), day.Day(), at.Hour(), at.Minute(), 0, 0, day.Location())
+}
+
+func (s *PickupSlot) BeforeSave(tx *gorm.DB) (err error) {
+	start, errStart := time.Parse("15:04", s.Start)
+	end, errEnd := time.Parse("15:04", s.End)
+	if errStart != nil || errEnd != nil {
+		return errors.New("slot times must be HH:MM")
+	}
+	if !end.After(start) {
+		return errors.New("slot ends before it starts")
+	}
+	if s.MaxOrders < 0 || s.MaxItems < 0 {
+		return errors.New("slot limits must not be negative")
+	}
+	// Keep the stored times comparable as text.
+	s.Start, s.End = start.Format("15:04"), end.Format("15:04")
+	return nil
+}
+
+func (t *Tag) BeforeSave(tx *gorm.DB) (err error) {
+	switch t.Kind {
+	case Allergen, Dietary:
+		return nil
+	default:
+		return errors.New("invalid tag kind")
+	}
+}
+
 func (u *User) BeforeSave(tx *gorm.DB) (err error) {
 	switch u.Role {
 	case Admin, Client:
@@ -0,0 +1,161 @@
+package models_test
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCanTransition(t *testing.T) {
+	statuses := []models.Status{models.Preparing, models.Ready, models.Completed, models.Canceled}
+	allowed := map[[2]models.Status]bool{
+		{models.Preparing, models.Ready}:    true,
+		{models.Preparing, models.Canceled}: true,
+		{models.Ready, models.Completed}:    true,
+		{models.Ready, models.Canceled}:     true,
+	}
+	for _, from := range statuses {
+		for _, to := range statuses {
+			if got := models.CanTransition(from, to); got != allowed[[2]models.Status{from, to}] {
+				t.Errorf("CanTransition(%s, %s) = %v", from, to, got)
+			}
+		}
+	}
+}
+
+func TestOrderStatusUpdates(t *testing.T) {
+	db := testdb.Open(t, "models")
+
+	user := models.User{Username: "client", Email: "client@example.com", Role: models.Client}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	admin := models.User{Username: "admin", Email: "admin@example.com", Role: models.Admin}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&models.Order{UserID: user.ID, OrderStatus: models.Ready}).Error; !errors.Is(err, models.ErrInvalidStatusTransition) {
+		t.Fatalf("creating a ready order: err = %v, want ErrInvalidStatusTransition", err)
+	}
+
+	order := models.Order{UserID: user.ID, OrderStatus: models.Preparing, StatusChangedBy: user.ID}
+	if err := db.Create(&order).Error; err != nil {
+		t.Fatal(err)
+	}
+	steps := []struct {
+		to models.Status
+		by uint
+		ok bool
+	}{
+		{models.Completed, admin.ID, false},
+		{models.Ready, admin.ID, true},
+		{models.Preparing, admin.ID, false},
+		// A change without a known user is recorded without one rather
+		// than blamed on the customer.
+		{models.Completed, 0, true},
+		{models.Canceled, admin.ID, false},
+	}
+	for _, step := range steps {
+		var current models.Order
+		if err := db.First(&current, order.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		from := current.OrderStatus
+		current.OrderStatus = step.to
+		current.StatusChangedBy = step.by
+		err := db.Save(&current).Error
+		if step.ok && err != nil {
+			t.Errorf("%s -> %s: %v", from, step.to, err)
+		}
+		if !step.ok && !errors.Is(err, models.ErrInvalidStatusTransition) {
+			t.Errorf("%s -> %s: err = %v, want ErrInvalidStatusTransition", from, step.to, err)
+		}
+	}
+
+	var history []models.OrderStatusHistory
+	if err := db.Where("order_id = ?", order.ID).Order("id").Find(&history).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		status models.Status
+		by     uint
+	}{
+		{models.Preparing, user.ID},
+		{models.Ready, admin.ID},
+		{models.Completed, 0},
+	}
+	if len(history) != len(want) {
+		t.Fatalf("history has %d rows, want %d", len(history), len(want))
+	}
+	for i, entry := range history {
+		if entry.ToStatus != want[i].status {
+			t.Errorf("history[%d] = %s, want %s", i, entry.ToStatus, want[i].status)
+		}
+		var by uint
+		if entry.ChangedByID != nil {
+			by = *entry.ChangedByID
+		}
+		if by != want[i].by {
+			t.Errorf("history[%d] changed by %d, want %d", i, by, want[i].by)
+		}
+	}
+}
+
+func TestPickupNumbersDoNotWaitForOpenOrders(t *testing.T) {
+	db := testdb.Open(t, "models")
+	user := models.User{Username: "client", Email: "client@example.com", Role: models.Client}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// An order whose transaction is still open must not hold up the next one.
+	open := db.Begin()
+	defer open.Rollback()
+	first := models.Order{UserID: user.ID, OrderStatus: models.Preparing}
+	if err := open.Create(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	second := models.Order{UserID: user.ID, OrderStatus: models.Preparing}
+	go func() { done <- db.Create(&second).Error }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("placing an order waited for another open order")
+	}
+	if first.PickupNumber != 1 || second.PickupNumber != 2 {
+		t.Errorf("pickup numbers = %d, %d, want 1, 2", first.PickupNumber, second.PickupNumber)
+	}
+
+	// Numbers stay unique when orders are placed at the same time.
+	const orders = 10
+	numbers := make(chan int, orders)
+	var wg sync.WaitGroup
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			order := models.Order{UserID: user.ID, OrderStatus: models.Preparing}
+			if err := db.Create(&order).Error; err != nil {
+				t.Error(err)
+				return
+			}
+			numbers <- order.PickupNumber
+		}()
+	}
+	wg.Wait()
+	close(numbers)
+	seen := map[int]bool{}
+	for number := range numbers {
+		if seen[number] {
+			t.Errorf("pickup number %d given out twice", number)
+		}
+		seen[number] = true
+	}
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+)
+
+var (
+	ErrUnsupportedImage = errors.New("unsupported image type, expected JPEG or PNG")
+	ErrImageTooLarge    = errors.New("image dimensions are too large")
+)
+
+// maxImagePixels bounds the width times height of an image that is decoded,
+// since a small compressed file can expand to gigabytes of pixels.
+const maxImagePixels = 40_000_000
+
+// ImageVariant is an encoded copy of an uploaded image.
+type ImageVariant struct {
+	Data      []byte
+	Extension string
+}
+
+// DetectImageType sniffs the content type of data and reports whether it is
+// an accepted image format.
+func DetectImageType(data []byte) (string, bool) {
+	contentType := http.DetectContentType(data)
+	return contentType, contentType == "image/jpeg" || contentType == "image/png"
+}
+
+// ResizeImage decodes data and returns it scaled down to fit in maxSize x
+// maxSize, keeping the aspect ratio. Images that already fit are re-encoded
+// at their original size. PNGs stay PNG, everything else becomes JPEG.
+// Images larger than maxImagePixels are rejected with ErrImageTooLarge
+// before they are decoded.
+func ResizeImage(data []byte, maxSize int) (ImageVariant, error) {
+	contentType, ok := DetectImageType(data)
+	if !ok {
+		return ImageVariant{}, ErrUnsupportedImage
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return ImageVariant{}, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width > maxImagePixels/config.Height {
+		return ImageVariant{}, ErrImageTooLarge
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ImageVariant{}, err
+	}
+
+	bounds := src.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > maxSize || height > maxSize {
+		if width >= height {
+			height = height * maxSize / width
+			width = maxSize
+		} else {
+			width = width * maxSize / height
+			height = maxSize
+		}
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if contentType == "image/png" {
+		if err := png.Encode(&buf, dst); err != nil {
+			return ImageVariant{}, err
+		}
+		return ImageVariant{Data: buf.Bytes(), Extension: ".png"}, nil
+	}
+	if err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return ImageVariant{}, err
+	}
+	return ImageVariant{Data: buf.Bytes(), Extension: ".jpg"}, nil
+}
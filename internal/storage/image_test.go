@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResizeImage(t *testing.T) {
+	variant, err := ResizeImage(encodePNG(t, 100, 50), 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant.Extension != ".png" {
+		t.Errorf("extension = %q, want .png", variant.Extension)
+	}
+	config, err := png.DecodeConfig(bytes.NewReader(variant.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.Width != 40 || config.Height != 20 {
+		t.Errorf("size = %dx%d, want 40x20", config.Width, config.Height)
+	}
+}
+
+func TestResizeImageRejectsTooManyPixels(t *testing.T) {
+	// A tiny PNG whose header claims 20000x20000 pixels.
+	data := encodePNG(t, 1, 1)
+	ihdr := data[8:]
+	binary.BigEndian.PutUint32(ihdr[8:12], 20000)
+	binary.BigEndian.PutUint32(ihdr[12:16], 20000)
+	binary.BigEndian.PutUint32(ihdr[21:25], crc32.ChecksumIEEE(ihdr[4:21]))
+
+	if _, err := ResizeImage(data, 1200); !errors.Is(err, ErrImageTooLarge) {
+		t.Fatalf("err = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestResizeImageRejectsUnsupportedType(t *testing.T) {
+	if _, err := ResizeImage([]byte("GIF89a"), 1200); !errors.Is(err, ErrUnsupportedImage) {
+		t.Fatalf("err = %v, want ErrUnsupportedImage", err)
+	}
+}
@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Storage keeps uploaded files and tells where they are served from.
+type Storage interface {
+	// Put stores data under key and returns the URL it is served from.
+	Put(key string, data []byte) (string, error)
+	// Delete removes a file by the URL returned from Put.
+	Delete(url string) error
+}
+
+// LocalStorage writes files under Dir; the router serves Dir at URLPrefix.
+type LocalStorage struct {
+	Dir       string
+	URLPrefix string
+}
+
+func NewLocalStorage(dir string, urlPrefix string) *LocalStorage {
+	return &LocalStorage{Dir: dir, URLPrefix: strings.TrimSuffix(urlPrefix, "/")}
+}
+
+func (s *LocalStorage) Put(key string, data []byte) (string, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(filePath, data, 0o644); err != nil {
+		return "", err
+	}
+	return path.Join(s.URLPrefix, key), nil
+}
+
+func (s *LocalStorage) Delete(url string) error {
+	if !strings.HasPrefix(url, s.URLPrefix+"/") {
+		return errors.New("file is not in this storage")
+	}
+	filePath, err := s.filePath(strings.TrimPrefix(url, s.URLPrefix+"/"))
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// filePath maps key to a path inside Dir, refusing keys that escape it.
+func (s *LocalStorage) filePath(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("invalid file key")
+	}
+	return filepath.Join(s.Dir, filepath.FromSlash(cleaned)), nil
+}
@@ -0,0 +1,76 @@
+// Package testdb connects tests to the PostgreSQL database named by
+// TEST_DATABASE_URL. Tests that need it are skipped when it is not set.
+package testdb
+
+import (
+	"final_project/initializers"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Open migrates and empties the schema of the test package, points
+// initializers.DB at it and returns it. Each package gets its own schema so
+// packages can be tested in parallel against one database.
+func Open(t testing.TB, schema string) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	if err := admin.Exec(fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %q", schema)).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	closeDB(admin)
+
+	db, err := gorm.Open(postgres.Open(withSearchPath(dsn, schema)), config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { closeDB(db) })
+
+	if err := initializers.Migrate(db); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	tables, err := db.Migrator().GetTables()
+	if err != nil {
+		t.Fatalf("list tables: %v", err)
+	}
+	if err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	initializers.DB = db
+	return db
+}
+
+func withSearchPath(dsn, schema string) string {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			query := u.Query()
+			query.Set("search_path", schema)
+			u.RawQuery = query.Encode()
+			return u.String()
+		}
+	}
+	return dsn + " search_path=" + schema
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
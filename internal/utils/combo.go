@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"fmt"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrComboNotFound       = errors.New("combo not found")
+	ErrComboUnavailable    = errors.New("combo is not available")
+	ErrInvalidComboChoices = errors.New("invalid combo choices")
+)
+
+type ComboChoice struct {
+	SlotID uint `json:"slot_id"`
+	ItemID uint `json:"item_id"`
+}
+
+type ComboLine struct {
+	ComboID  uint
+	Quantity int
+	Choices  []ComboChoice
+}
+
+// ResolvedChoice is a combo slot together with the menu item chosen for it.
+type ResolvedChoice struct {
+	Slot models.ComboSlot
+	Item models.Menu
+}
+
+// ResolveCombo loads the combo and checks that choices fill every slot
+// exactly once with one of the slot's menu items. The choices are returned in
+// slot order.
+func ResolveCombo(db *gorm.DB, comboID uint, choices []ComboChoice) (models.Combo, []ResolvedChoice, error) {
+	var combo models.Combo
+	err := db.Preload("Slots", func(db *gorm.DB) *gorm.DB { return db.Order("sort_order, id") }).Preload("Slots.Items").First(&combo, comboID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return combo, nil, ErrComboNotFound
+		}
+		return combo, nil, err
+	}
+	if !combo.IsAvailable {
+		return combo, nil, ErrComboUnavailable
+	}
+
+	chosen := make(map[uint]uint, len(choices))
+	for _, choice := range choices {
+		if _, ok := chosen[choice.SlotID]; ok {
+			return combo, nil, fmt.Errorf("%w: slot %d chosen twice", ErrInvalidComboChoices, choice.SlotID)
+		}
+		chosen[choice.SlotID] = choice.ItemID
+	}
+	if len(chosen) != len(combo.Slots) {
+		return combo, nil, fmt.Errorf("%w: choose one item for each of the %d slots", ErrInvalidComboChoices, len(combo.Slots))
+	}
+
+	resolved := make([]ResolvedChoice, 0, len(combo.Slots))
+	for _, slot := range combo.Slots {
+		itemID, ok := chosen[slot.ID]
+		if !ok {
+			return combo, nil, fmt.Errorf("%w: no item chosen for %s", ErrInvalidComboChoices, slot.Name)
+		}
+		var item *models.Menu
+		for i := range slot.Items {
+			if slot.Items[i].ID == itemID {
+				item = &slot.Items[i]
+				break
+			}
+		}
+		if item == nil {
+			return combo, nil, fmt.Errorf("%w: item %d cannot be chosen for %s", ErrInvalidComboChoices, itemID, slot.Name)
+		}
+		owner := slot
+		owner.Items = nil
+		resolved = append(resolved, ResolvedChoice{Slot: owner, Item: *item})
+	}
+	return combo, resolved, nil
+}
+
+// ChoicesKey identifies a set of combo choices regardless of their order.
+func ChoicesKey(choices []ComboChoice) string {
+	parts := make([]string, 0, len(choices))
+	for _, choice := range choices {
+		parts = append(parts, fmt.Sprintf("%d:%d", choice.SlotID, choice.ItemID))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ",")
+}
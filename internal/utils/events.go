@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"fmt"
+	"time"
+)
+
+// KitchenTopic carries every order event, for the kitchen display.
+const KitchenTopic = "kitchen"
+
+// UserTopic carries the status changes of the user's orders.
+func UserTopic(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// Types of the order events.
+const (
+	OrderCreated       = "order.created"
+	OrderStatusChanged = "order.status"
+	OrderDeleted       = "order.deleted"
+)
+
+type OrderEvent struct {
+	OrderID        uint          `json:"order_id"`
+	UserID         uint          `json:"user_id"`
+	Status         models.Status `json:"status"`
+	PreviousStatus models.Status `json:"previous_status,omitempty"`
+	At             time.Time     `json:"at"`
+}
+
+// PublishOrderEvent announces a change to the order. Call it only once the
+// change is committed.
+func PublishOrderEvent(eventType string, order models.Order, previousStatus models.Status) {
+	event := Event{Type: eventType, Data: OrderEvent{
+		OrderID:        order.ID,
+		UserID:         order.UserID,
+		Status:         order.OrderStatus,
+		PreviousStatus: previousStatus,
+		At:             time.Now(),
+	}}
+	Events.Publish(KitchenTopic, event)
+	if eventType == OrderStatusChanged {
+		Events.Publish(UserTopic(order.UserID), event)
+	}
+}
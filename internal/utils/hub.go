@@ -0,0 +1,65 @@
+package utils
+
+import "sync"
+
+// subscriberBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it.
+const subscriberBuffer = 32
+
+// Event is a message published on a hub topic.
+type Event struct {
+	Type string
+	Data interface{}
+}
+
+// Hub passes events from publishers to the subscribers of a topic within the
+// process. Publishing never blocks: a subscriber that does not keep up misses
+// events rather than holding up the publisher.
+type Hub struct {
+	mu     sync.RWMutex
+	topics map[string]map[chan Event]struct{}
+}
+
+// Events is the hub shared by the whole application.
+var Events = NewHub()
+
+func NewHub() *Hub {
+	return &Hub{topics: map[string]map[chan Event]struct{}{}}
+}
+
+// Subscribe returns a channel receiving the events published on the topic
+// from now on, and a function that ends the subscription and closes it.
+func (h *Hub) Subscribe(topic string) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = map[chan Event]struct{}{}
+	}
+	h.topics[topic][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			delete(h.topics[topic], ch)
+			if len(h.topics[topic]) == 0 {
+				delete(h.topics, topic)
+			}
+			h.mu.Unlock()
+			close(ch)
+		})
+	}
+}
+
+// Publish sends the event to every current subscriber of the topic.
+func (h *Hub) Publish(topic string, event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for ch := range h.topics[topic] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
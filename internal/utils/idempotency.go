@@ -0,0 +1,224 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"final_project/initializers"
+	"final_project/internal/models"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// IdempotencyKeyTTL is how long a response is kept for retries.
+	IdempotencyKeyTTL = 24 * time.Hour
+	// IdempotencyLease is how long a request may run before its key is
+	// thought to be abandoned, e.g. by a crashed server, and a retry may
+	// claim it.
+	IdempotencyLease = time.Minute
+
+	maxIdempotencyKeyLength = 255
+	// storeAttempts is how often storing a successful response is tried.
+	storeAttempts = 3
+	// idempotencyKeyContextKey holds the claimed key of the request.
+	idempotencyKeyContextKey = "idempotency_key"
+)
+
+// IdempotencyMiddleware makes a route safe to retry. When a request carries
+// an Idempotency-Key header, its response is stored with the key, and a retry
+// with the same key and request gets that response again instead of being
+// handled twice. Reusing a key for a different request is rejected with 422.
+// Server errors are not stored, so such requests can be retried. It must run
+// after AuthMiddleware, as keys belong to a user. Handlers that change data
+// should store their response with SaveIdempotentResponse in the same
+// transaction, so the response is kept exactly when the changes are.
+func IdempotencyMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency key is too long"})
+			return
+		}
+		userID, _ := c.Get("ID")
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.New()
+		hash.Write([]byte(c.Request.Method + " " + c.Request.URL.RequestURI() + "\n"))
+		hash.Write(body)
+		requestHash := hex.EncodeToString(hash.Sum(nil))
+
+		record, claimed, err := claimIdempotencyKey(initializers.DB, userID.(uint), key, requestHash)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check idempotency key"})
+			return
+		}
+		if !claimed {
+			switch {
+			case record.RequestHash != requestHash:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency key was already used for a different request"})
+			case record.StatusCode == 0:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this idempotency key is still being processed"})
+			default:
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(record.StatusCode, record.ContentType, record.Response)
+				c.Abort()
+			}
+			return
+		}
+
+		// The key is given up when the handler fails or panics so that the
+		// request can be retried, unless the handler already stored its
+		// response.
+		keep := false
+		defer func() {
+			if !keep {
+				initializers.DB.Where("status_code = 0").Delete(&record)
+			}
+		}()
+		c.Set(idempotencyKeyContextKey, record)
+
+		writer := &recordingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		status := writer.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		for attempt := 0; attempt < storeAttempts && !keep; attempt++ {
+			err = initializers.DB.Model(&models.IdempotencyKey{}).
+				Where("id = ? AND status_code = 0", record.ID).
+				Updates(map[string]interface{}{
+					"status_code":  status,
+					"content_type": writer.Header().Get("Content-Type"),
+					"response":     writer.body.Bytes(),
+				}).Error
+			keep = err == nil
+		}
+		// The changes of a successful request may be committed already, so
+		// its key is kept even when the response could not be stored. A
+		// retry then gets 409 instead of repeating the request.
+		if status < http.StatusMultipleChoices {
+			keep = true
+		}
+	}
+}
+
+// SaveIdempotentResponse stores the response of a request sent with an
+// Idempotency-Key within tx, so that it is committed together with the
+// changes the request made. It must be given what the handler then writes
+// with c.JSON. Requests without a key are left alone.
+func SaveIdempotentResponse(tx *gorm.DB, c *gin.Context, status int, obj interface{}) error {
+	value, ok := c.Get(idempotencyKeyContextKey)
+	if !ok {
+		return nil
+	}
+	record := value.(models.IdempotencyKey)
+	response, err := json.Marshal(obj)
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.IdempotencyKey{}).Where("id = ?", record.ID).Updates(map[string]interface{}{
+		"status_code":  status,
+		"content_type": "application/json; charset=utf-8",
+		"response":     response,
+	}).Error
+}
+
+// claimIdempotencyKey stores the key for the request unless the user already
+// used it. It returns the stored key and whether this request claimed it.
+// An expired key is deleted first so that it is claimed again, and the key
+// of a request that has been running for longer than IdempotencyLease is
+// taken over by a retry of the same request.
+func claimIdempotencyKey(db *gorm.DB, userID uint, key, requestHash string) (models.IdempotencyKey, bool, error) {
+	now := time.Now()
+	err := db.Where("user_id = ? AND key = ? AND created_at < ?", userID, key, now.Add(-IdempotencyKeyTTL)).
+		Delete(&models.IdempotencyKey{}).Error
+	if err != nil {
+		return models.IdempotencyKey{}, false, err
+	}
+
+	record := models.IdempotencyKey{UserID: userID, Key: key, RequestHash: requestHash, CreatedAt: now}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+	if result.Error != nil {
+		return record, false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	var existing models.IdempotencyKey
+	if err := db.Where("user_id = ? AND key = ?", userID, key).First(&existing).Error; err != nil {
+		// The key was given up by a failed request in the meantime.
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return claimIdempotencyKey(db, userID, key, requestHash)
+		}
+		return existing, false, err
+	}
+	if existing.StatusCode != 0 || existing.RequestHash != requestHash || time.Since(existing.CreatedAt) < IdempotencyLease {
+		return existing, false, nil
+	}
+	// Only one of several retries gets to take over the key.
+	result = db.Model(&models.IdempotencyKey{}).
+		Where("id = ? AND status_code = 0 AND created_at = ?", existing.ID, existing.CreatedAt).
+		Update("created_at", now)
+	if result.Error != nil {
+		return existing, false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return claimIdempotencyKey(db, userID, key, requestHash)
+	}
+	existing.CreatedAt = now
+	return existing, true, nil
+}
+
+// DeleteExpiredIdempotencyKeys removes the keys older than IdempotencyKeyTTL.
+func DeleteExpiredIdempotencyKeys(db *gorm.DB) error {
+	return db.Where("created_at < ?", time.Now().Add(-IdempotencyKeyTTL)).Delete(&models.IdempotencyKey{}).Error
+}
+
+// CleanUpIdempotencyKeys deletes expired keys every interval. It never
+// returns, so it is started in its own goroutine.
+func CleanUpIdempotencyKeys(db *gorm.DB, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := DeleteExpiredIdempotencyKeys(db); err != nil {
+			log.Printf("Failed to delete expired idempotency keys: %v", err)
+		}
+	}
+}
+
+// recordingWriter keeps a copy of the response body.
+type recordingWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordingWriter) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *recordingWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type idempotentRoute struct {
+	router *gin.Engine
+	calls  atomic.Int32
+	// status is returned by the handler; block, when set, holds it until closed.
+	status  int
+	entered chan struct{}
+	block   chan struct{}
+}
+
+func newIdempotentRoute(userID uint) *idempotentRoute {
+	gin.SetMode(gin.TestMode)
+	route := &idempotentRoute{router: gin.New(), status: http.StatusCreated}
+	auth := func(c *gin.Context) { c.Set("ID", userID) }
+	route.router.POST("/orders", auth, IdempotencyMiddleware(), func(c *gin.Context) {
+		n := route.calls.Add(1)
+		if route.block != nil {
+			route.entered <- struct{}{}
+			<-route.block
+		}
+		c.JSON(route.status, gin.H{"call": n})
+	})
+	return route
+}
+
+func (r *idempotentRoute) post(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(body))
+	req.Header.Set("Idempotency-Key", key)
+	w := httptest.NewRecorder()
+	r.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyReplay(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+	other := createUser(t, db, "other", models.Client)
+	route := newIdempotentRoute(user.ID)
+
+	first := route.post("key-1", `{"slot_id":1}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	retry := route.post("key-1", `{"slot_id":1}`)
+	if retry.Code != http.StatusCreated || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry = %d %s, want %d %s", retry.Code, retry.Body, first.Code, first.Body)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("retry is not marked as replayed")
+	}
+	if n := route.calls.Load(); n != 1 {
+		t.Errorf("handler ran %d times, want 1", n)
+	}
+
+	if w := route.post("key-1", `{"slot_id":2}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different body: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	// Keys belong to a user.
+	if w := newIdempotentRoute(other.ID).post("key-1", `{"slot_id":2}`); w.Code != http.StatusCreated {
+		t.Errorf("other user's key: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+
+	// An expired key is handled as a new request.
+	expired := time.Now().Add(-IdempotencyKeyTTL - time.Minute)
+	if err := db.Model(&models.IdempotencyKey{}).Where("key = ?", "key-1").Update("created_at", expired).Error; err != nil {
+		t.Fatal(err)
+	}
+	again := route.post("key-1", `{"slot_id":2}`)
+	if again.Code != http.StatusCreated || again.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("expired key: status = %d, replayed = %q", again.Code, again.Header().Get("Idempotent-Replayed"))
+	}
+	if n := route.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+
+	// Claiming a key leaves the expired keys of others to the cleanup.
+	var keys []models.IdempotencyKey
+	if err := db.Order("user_id").Find(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("keys = %d, want 2", len(keys))
+	}
+	if err := DeleteExpiredIdempotencyKeys(db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Find(&keys).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].UserID != user.ID {
+		t.Errorf("keys left after cleanup = %+v, want only the fresh key of %s", keys, user.Username)
+	}
+}
+
+func TestIdempotencyInFlight(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	route := newIdempotentRoute(createUser(t, db, "client", models.Client).ID)
+	route.entered = make(chan struct{})
+	route.block = make(chan struct{})
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() { done <- route.post("key-1", `{}`) }()
+	select {
+	case <-route.entered:
+	case w := <-done:
+		t.Fatalf("first request finished without reaching the handler: %d %s", w.Code, w.Body)
+	}
+
+	if w := route.post("key-1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("retry while in flight: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	close(route.block)
+	if w := <-done; w.Code != http.StatusCreated {
+		t.Errorf("first request: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyServerErrorIsNotStored(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	route := newIdempotentRoute(createUser(t, db, "client", models.Client).ID)
+	route.status = http.StatusInternalServerError
+
+	if w := route.post("key-1", `{}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusInternalServerError)
+	}
+	route.status = http.StatusCreated
+	if w := route.post("key-1", `{}`); w.Code != http.StatusCreated || w.Header().Get("Idempotent-Replayed") != "" {
+		t.Errorf("retry after a server error: status = %d, replayed = %q", w.Code, w.Header().Get("Idempotent-Replayed"))
+	}
+	if n := route.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+}
+
+func TestSaveIdempotentResponseInTransaction(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	calls := 0
+	commit := true
+	router.POST("/orders", func(c *gin.Context) { c.Set("ID", user.ID) }, IdempotencyMiddleware(), func(c *gin.Context) {
+		calls++
+		response := gin.H{"call": calls}
+		tx := db.Begin()
+		if err := SaveIdempotentResponse(tx, c, http.StatusCreated, response); err != nil {
+			tx.Rollback()
+			t.Error(err)
+			return
+		}
+		if !commit {
+			tx.Rollback()
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rolled back"})
+			return
+		}
+		tx.Commit()
+		c.JSON(http.StatusCreated, response)
+	})
+	post := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/orders", strings.NewReader(`{}`))
+		req.Header.Set("Idempotency-Key", key)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := post("key-1")
+	var stored models.IdempotencyKey
+	if err := db.Where("key = ?", "key-1").First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StatusCode != http.StatusCreated || string(stored.Response) != first.Body.String() {
+		t.Errorf("stored = %d %s, want %d %s", stored.StatusCode, stored.Response, first.Code, first.Body)
+	}
+	if retry := post("key-1"); retry.Body.String() != first.Body.String() || calls != 1 {
+		t.Errorf("retry = %s after %d calls, want the first response", retry.Body, calls)
+	}
+
+	// A response saved in a transaction that is rolled back is not kept.
+	commit = false
+	failed := post("key-2")
+	if err := db.Where("key = ?", "key-2").First(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.StatusCode != http.StatusBadRequest || string(stored.Response) != failed.Body.String() {
+		t.Errorf("stored = %d %s, want %d %s", stored.StatusCode, stored.Response, failed.Code, failed.Body)
+	}
+}
+
+func TestIdempotencyAbandonedRequest(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+	route := newIdempotentRoute(user.ID)
+
+	// A request whose server died left its key in flight.
+	abandoned := models.IdempotencyKey{UserID: user.ID, Key: "key-1", CreatedAt: time.Now()}
+	first := route.post("key-2", `{}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", first.Code, http.StatusCreated)
+	}
+	var done models.IdempotencyKey
+	if err := db.Where("key = ?", "key-2").First(&done).Error; err != nil {
+		t.Fatal(err)
+	}
+	abandoned.RequestHash = done.RequestHash
+	if err := db.Create(&abandoned).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if w := route.post("key-1", `{}`); w.Code != http.StatusConflict {
+		t.Errorf("retry within the lease: status = %d, want %d", w.Code, http.StatusConflict)
+	}
+	started := time.Now().Add(-IdempotencyLease - time.Second)
+	if err := db.Model(&abandoned).Update("created_at", started).Error; err != nil {
+		t.Fatal(err)
+	}
+	if w := route.post("key-1", `{"other":true}`); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("different request after the lease: status = %d, want %d", w.Code, http.StatusUnprocessableEntity)
+	}
+	if w := route.post("key-1", `{}`); w.Code != http.StatusCreated {
+		t.Errorf("retry after the lease: status = %d, want %d", w.Code, http.StatusCreated)
+	}
+	if n := route.calls.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2", n)
+	}
+	if w := route.post("key-1", `{}`); w.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("the response of the retry was not stored")
+	}
+}
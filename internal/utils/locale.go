@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// DefaultLocale is the locale of the names stored on menu items and
+// categories themselves. It is set by the default_locale environment
+// variable and is English otherwise.
+func DefaultLocale() string {
+	if locale := os.Getenv("default_locale"); SupportedLocale(locale) {
+		return locale
+	}
+	return "en"
+}
+
+func SupportedLocale(locale string) bool {
+	for _, supported := range models.Locales {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// RequestLocale picks the locale of the response from the lang query
+// parameter, then the Accept-Language header, then the default locale.
+func RequestLocale(c *gin.Context) string {
+	if lang := strings.ToLower(strings.TrimSpace(c.Query("lang"))); SupportedLocale(lang) {
+		return lang
+	}
+
+	best, bestWeight := "", 0.0
+	for _, part := range strings.Split(c.GetHeader("Accept-Language"), ",") {
+		fields := strings.Split(part, ";")
+		// Only the language matters, so "ru-RU" counts as "ru".
+		locale := strings.ToLower(strings.TrimSpace(fields[0]))
+		if i := strings.IndexAny(locale, "-_"); i >= 0 {
+			locale = locale[:i]
+		}
+		weight := 1.0
+		for _, param := range fields[1:] {
+			if value, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+				if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+					weight = parsed
+				}
+			}
+		}
+		if SupportedLocale(locale) && weight > bestWeight {
+			best, bestWeight = locale, weight
+		}
+	}
+	if best != "" {
+		return best
+	}
+	return DefaultLocale()
+}
+
+// MenuTranslations returns the translation of each menu item in the locale,
+// or in the default locale when there is none. Items without either are left
+// out and keep their own name.
+func MenuTranslations(db *gorm.DB, itemIDs []uint, locale string) (map[uint]models.MenuTranslation, error) {
+	translations := map[uint]models.MenuTranslation{}
+	if len(itemIDs) == 0 {
+		return translations, nil
+	}
+	var rows []models.MenuTranslation
+	if err := db.Where("item_id IN ? AND locale IN ?", itemIDs, []string{locale, DefaultLocale()}).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if _, ok := translations[row.ItemID]; !ok || row.Locale == locale {
+			translations[row.ItemID] = row
+		}
+	}
+	return translations, nil
+}
+
+// TranslateMenuItems replaces the names and descriptions of the items with
+// their translations in the locale.
+func TranslateMenuItems(db *gorm.DB, items []models.Menu, locale string) error {
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	translations, err := MenuTranslations(db, itemIDs, locale)
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		items[i].Name, items[i].Description = TranslatedMenuItem(translations, items[i])
+	}
+	return nil
+}
+
+// TranslatedMenuItem returns the name and description of the item from the
+// translations, falling back to the item's own for missing parts.
+func TranslatedMenuItem(translations map[uint]models.MenuTranslation, item models.Menu) (string, string) {
+	name, description := item.Name, item.Description
+	if translation, ok := translations[item.ID]; ok {
+		if translation.Name != "" {
+			name = translation.Name
+		}
+		if translation.Description != "" {
+			description = translation.Description
+		}
+	}
+	return name, description
+}
+
+// TranslateCategories replaces the category names with their translations in
+// the locale, or in the default locale when there is none.
+func TranslateCategories(db *gorm.DB, categories []models.Category, locale string) error {
+	if len(categories) == 0 {
+		return nil
+	}
+	categoryIDs := make([]uint, 0, len(categories))
+	for _, category := range categories {
+		categoryIDs = append(categoryIDs, category.ID)
+	}
+	var rows []models.CategoryTranslation
+	if err := db.Where("category_id IN ? AND locale IN ?", categoryIDs, []string{locale, DefaultLocale()}).Find(&rows).Error; err != nil {
+		return err
+	}
+	names := map[uint]string{}
+	for _, row := range rows {
+		if _, ok := names[row.CategoryID]; (!ok || row.Locale == locale) && row.Name != "" {
+			names[row.CategoryID] = row.Name
+		}
+	}
+	for i := range categories {
+		if name, ok := names[categories[i].ID]; ok {
+			categories[i].Name = name
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidModifiers = errors.New("invalid modifiers")
+
+// ResolveOptions checks the chosen option IDs against the modifier groups of
+// the menu item. It returns the chosen options, each with its Group set, and
+// the amount they add to the unit price.
+func ResolveOptions(db *gorm.DB, itemID uint, optionIDs []uint) ([]models.ModifierOption, decimal.Decimal, error) {
+	var groups []models.ModifierGroup
+	if err := db.Preload("Options").Where("item_id = ?", itemID).Order("sort_order, id").Find(&groups).Error; err != nil {
+		return nil, decimal.Zero, err
+	}
+
+	chosen := make(map[uint]bool, len(optionIDs))
+	for _, optionID := range optionIDs {
+		if chosen[optionID] {
+			return nil, decimal.Zero, fmt.Errorf("%w: option %d chosen twice", ErrInvalidModifiers, optionID)
+		}
+		chosen[optionID] = true
+	}
+
+	options := []models.ModifierOption{}
+	delta := decimal.Zero
+	for _, group := range groups {
+		owner := group
+		owner.Options = nil
+		count := 0
+		for _, option := range group.Options {
+			if !chosen[option.ID] {
+				continue
+			}
+			option.Group = &owner
+			options = append(options, option)
+			delta = delta.Add(option.PriceDelta)
+			count++
+		}
+		if count < group.MinSelect || count > group.MaxSelect {
+			return nil, decimal.Zero, fmt.Errorf("%w: choose between %d and %d options for %s", ErrInvalidModifiers, group.MinSelect, group.MaxSelect, group.Name)
+		}
+	}
+	if len(options) != len(chosen) {
+		return nil, decimal.Zero, fmt.Errorf("%w: option does not belong to this item", ErrInvalidModifiers)
+	}
+	return options, delta, nil
+}
+
+// OptionsKey identifies a combination of options regardless of their order.
+func OptionsKey(options []models.ModifierOption) string {
+	ids := make([]int, 0, len(options))
+	for _, option := range options {
+		ids = append(ids, int(option.ID))
+	}
+	sort.Ints(ids)
+	parts := make([]string, 0, len(ids))
+	for _, id := range ids {
+		parts = append(parts, strconv.Itoa(id))
+	}
+	return strings.Join(parts, ",")
+}
+
+// OptionLabels lists the kitchen labels of options loaded with their Group.
+func OptionLabels(options []models.ModifierOption) []string {
+	labels := make([]string, 0, len(options))
+	for _, option := range options {
+		kind := models.Extra
+		if option.Group != nil {
+			kind = option.Group.Kind
+		}
+		labels = append(labels, models.ModifierLabel(kind, option.Name))
+	}
+	return labels
+}
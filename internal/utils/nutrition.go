@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DailyNutrition is what a user ate on one day.
+type DailyNutrition struct {
+	Date      string           `json:"date"`
+	Orders    int              `json:"orders"`
+	Nutrition models.Nutrition `json:"nutrition"`
+}
+
+// NutritionIntake sums the nutrition of the user's completed orders placed
+// between from and to, inclusive, per day. Items and combo choices are both
+// counted.
+func NutritionIntake(db *gorm.DB, userID uint, from, to time.Time) ([]DailyNutrition, error) {
+	var rows []struct {
+		Day          time.Time
+		Orders       int
+		Kcal         float64
+		Protein      float64
+		Fat          float64
+		Carbs        float64
+		PortionGrams int
+	}
+	err := db.Raw(`
+		SELECT day, COUNT(DISTINCT order_id) AS orders,
+			SUM(kcal * quantity) AS kcal, SUM(protein * quantity) AS protein, SUM(fat * quantity) AS fat,
+			SUM(carbs * quantity) AS carbs, SUM(portion_grams * quantity) AS portion_grams
+		FROM (
+			SELECT DATE(orders.created_at) AS day, orders.id AS order_id, order_details.quantity,
+				menus.kcal, menus.protein, menus.fat, menus.carbs, menus.portion_grams
+			FROM order_details
+			JOIN orders ON orders.id = order_details.order_id
+			JOIN menus ON menus.id = order_details.item_id
+			WHERE orders.user_id = @user AND orders.order_status = @status AND orders.created_at >= @from AND orders.created_at < @to
+			UNION ALL
+			SELECT DATE(orders.created_at), orders.id, order_combos.quantity,
+				menus.kcal, menus.protein, menus.fat, menus.carbs, menus.portion_grams
+			FROM order_combo_choices
+			JOIN order_combos ON order_combos.id = order_combo_choices.order_combo_id
+			JOIN orders ON orders.id = order_combos.order_id
+			JOIN menus ON menus.id = order_combo_choices.item_id
+			WHERE orders.user_id = @user AND orders.order_status = @status AND orders.created_at >= @from AND orders.created_at < @to
+		) AS eaten
+		GROUP BY day
+		ORDER BY day`,
+		map[string]interface{}{"user": userID, "status": models.Completed, "from": from, "to": to.AddDate(0, 0, 1)},
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]DailyNutrition, 0, len(rows))
+	for _, row := range rows {
+		nutrition := models.Nutrition{
+			Kcal:         row.Kcal,
+			Protein:      row.Protein,
+			Fat:          row.Fat,
+			Carbs:        row.Carbs,
+			PortionGrams: row.PortionGrams,
+		}
+		days = append(days, DailyNutrition{
+			Date:      row.Day.Format("2006-01-02"),
+			Orders:    row.Orders,
+			Nutrition: nutrition.Times(1),
+		})
+	}
+	return days, nil
+}
@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEmptyOrder         = errors.New("order has no items")
+	ErrInvalidQuantity    = errors.New("invalid quantity")
+	ErrProductNotFound    = errors.New("product not found")
+	ErrProductUnavailable = errors.New("product is not available")
+)
+
+// OrderItemError ties an order creation error to the menu item or combo that
+// caused it.
+type OrderItemError struct {
+	ItemID  uint
+	ComboID uint
+	Err     error
+}
+
+func (e *OrderItemError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OrderItemError) Unwrap() error {
+	return e.Err
+}
+
+type OrderLine struct {
+	ItemID    uint
+	Quantity  int
+	OptionIDs []uint
+}
+
+// OrderInput is everything a customer asks for in one order and the pickup
+// slot they want it in.
+type OrderInput struct {
+	Lines  []OrderLine
+	Combos []ComboLine
+	SlotID uint
+}
+
+// timeNow is when orders are placed; tests replace it.
+var timeNow = time.Now
+
+// CreateOrder books the pickup slot, validates every line and combo against
+// the menu at the pickup time, takes the stock and creates a preparing order
+// with its details. It must run inside tx so that a failing line leaves no
+// stock taken. Items that conflict with the user's allergens are not rejected
+// but reported in the order's Warnings.
+func CreateOrder(tx *gorm.DB, userID uint, input OrderInput) (models.Order, error) {
+	newOrder := models.Order{
+		UserID:          userID,
+		StatusChangedBy: userID,
+		OrderDetails:    []models.OrderDetail{},
+		CreatedAt:       timeNow(),
+		OrderStatus:     models.Preparing,
+	}
+	if len(input.Lines) == 0 && len(input.Combos) == 0 {
+		return newOrder, ErrEmptyOrder
+	}
+	if input.SlotID == 0 {
+		return newOrder, ErrSlotRequired
+	}
+	slot, err := LockSlot(tx, input.SlotID, newOrder.CreatedAt)
+	if err != nil {
+		return newOrder, err
+	}
+	pickupAt := slot.StartOn(newOrder.CreatedAt)
+
+	var totalPrice decimal.Decimal
+	portions := 0
+	takes := []stockTake{}
+	menuItems := make([]models.Menu, 0, len(input.Lines))
+	for _, line := range input.Lines {
+		if line.Quantity <= 0 {
+			return newOrder, &OrderItemError{ItemID: line.ItemID, Err: ErrInvalidQuantity}
+		}
+
+		menuItem, err := checkMenuItem(tx, line.ItemID, pickupAt)
+		if err != nil {
+			return newOrder, err
+		}
+		takes = append(takes, stockTake{ItemID: menuItem.ID, Quantity: line.Quantity})
+
+		options, priceDelta, err := ResolveOptions(tx, menuItem.ID, line.OptionIDs)
+		if err != nil {
+			if errors.Is(err, ErrInvalidModifiers) {
+				return newOrder, &OrderItemError{ItemID: line.ItemID, Err: err}
+			}
+			return newOrder, err
+		}
+		modifiers := make([]models.OrderDetailModifier, 0, len(options))
+		for _, option := range options {
+			modifiers = append(modifiers, models.OrderDetailModifier{
+				OptionID:   option.ID,
+				GroupKind:  option.Group.Kind,
+				GroupName:  option.Group.Name,
+				OptionName: option.Name,
+				PriceDelta: option.PriceDelta,
+			})
+		}
+
+		unitPrice := menuItem.Price.Add(priceDelta)
+		itemTotalCost := unitPrice.Mul(decimal.NewFromInt(int64(line.Quantity)))
+		newOrder.OrderDetails = append(newOrder.OrderDetails, models.OrderDetail{
+			ItemID:    line.ItemID,
+			Quantity:  line.Quantity,
+			UnitPrice: unitPrice,
+			TotalCost: itemTotalCost,
+			Modifiers: modifiers,
+		})
+		totalPrice = totalPrice.Add(itemTotalCost)
+		portions += line.Quantity
+		menuItems = append(menuItems, menuItem)
+	}
+
+	for _, line := range input.Combos {
+		if line.Quantity <= 0 {
+			return newOrder, &OrderItemError{ComboID: line.ComboID, Err: ErrInvalidQuantity}
+		}
+
+		combo, choices, err := ResolveCombo(tx, line.ComboID, line.Choices)
+		if err != nil {
+			if errors.Is(err, ErrComboNotFound) || errors.Is(err, ErrComboUnavailable) || errors.Is(err, ErrInvalidComboChoices) {
+				return newOrder, &OrderItemError{ComboID: line.ComboID, Err: err}
+			}
+			return newOrder, err
+		}
+
+		orderCombo := models.OrderCombo{
+			ComboID:   combo.ID,
+			Name:      combo.Name,
+			Quantity:  line.Quantity,
+			TotalCost: combo.Price.Mul(decimal.NewFromInt(int64(line.Quantity))),
+		}
+		for _, choice := range choices {
+			menuItem, err := checkMenuItem(tx, choice.Item.ID, pickupAt)
+			if err != nil {
+				var itemErr *OrderItemError
+				if errors.As(err, &itemErr) {
+					itemErr.ComboID = combo.ID
+				}
+				return newOrder, err
+			}
+			takes = append(takes, stockTake{ItemID: menuItem.ID, Quantity: line.Quantity, ComboID: combo.ID})
+			orderCombo.Choices = append(orderCombo.Choices, models.OrderComboChoice{
+				SlotID:   choice.Slot.ID,
+				SlotName: choice.Slot.Name,
+				ItemID:   menuItem.ID,
+				ItemName: menuItem.Name,
+			})
+			menuItems = append(menuItems, menuItem)
+			portions += line.Quantity
+		}
+		newOrder.OrderCombos = append(newOrder.OrderCombos, orderCombo)
+		totalPrice = totalPrice.Add(orderCombo.TotalCost)
+	}
+
+	if err := takeStock(tx, takes); err != nil {
+		return newOrder, err
+	}
+	if err := CheckSlotCapacity(tx, slot, portions, newOrder.CreatedAt); err != nil {
+		return newOrder, err
+	}
+	newOrder.PickupSlotID = &input.SlotID
+	newOrder.PickupAt = &pickupAt
+
+	newOrder.TotalPrice = totalPrice
+	if err := tx.Create(&newOrder).Error; err != nil {
+		return newOrder, err
+	}
+
+	warnings, err := AllergenWarnings(tx, userID, menuItems)
+	if err != nil {
+		return newOrder, err
+	}
+	newOrder.Warnings = warnings
+	return newOrder, nil
+}
+
+// checkMenuItem checks that the menu item can be ordered and is served at
+// the pickup time.
+func checkMenuItem(tx *gorm.DB, itemID uint, pickupAt time.Time) (models.Menu, error) {
+	menuItem := models.Menu{}
+	if err := tx.First(&menuItem, itemID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return menuItem, &OrderItemError{ItemID: itemID, Err: ErrProductNotFound}
+		}
+		return menuItem, err
+	}
+	if !menuItem.IsAvailable {
+		return menuItem, &OrderItemError{ItemID: itemID, Err: ErrProductUnavailable}
+	}
+	scheduled, err := IsScheduledAt(tx, menuItem.ID, pickupAt)
+	if err != nil {
+		return menuItem, err
+	}
+	if !scheduled {
+		return menuItem, &OrderItemError{ItemID: itemID, Err: ErrProductNotScheduled}
+	}
+	return menuItem, nil
+}
+
+// stockTake is quantity portions of a menu item needed by a line or combo.
+type stockTake struct {
+	ItemID   uint
+	Quantity int
+	ComboID  uint
+}
+
+// takeStock takes the portions from the stock in menu item order, so that
+// orders listing the same items in a different order lock their rows in the
+// same order and cannot deadlock.
+func takeStock(tx *gorm.DB, takes []stockTake) error {
+	sort.SliceStable(takes, func(i, j int) bool { return takes[i].ItemID < takes[j].ItemID })
+	for _, take := range takes {
+		if err := DecrementStock(tx, take.ItemID, take.Quantity); err != nil {
+			if errors.Is(err, ErrNotEnoughStock) {
+				return &OrderItemError{ItemID: take.ItemID, ComboID: take.ComboID, Err: err}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// OrderErrorResponse maps a CreateOrder error to the HTTP status and body
+// returned by the order endpoints.
+func OrderErrorResponse(err error) (int, gin.H) {
+	var itemErr *OrderItemError
+	if errors.As(err, &itemErr) {
+		body := gin.H{}
+		if itemErr.ItemID != 0 {
+			body["productID"] = itemErr.ItemID
+		}
+		if itemErr.ComboID != 0 {
+			body["comboID"] = itemErr.ComboID
+		}
+		switch {
+		case errors.Is(err, ErrInvalidQuantity):
+			body["error"] = "Invalid quantity"
+		case errors.Is(err, ErrProductNotFound):
+			body["error"] = "Product not found"
+		case errors.Is(err, ErrProductUnavailable):
+			body["error"] = "Product is not available"
+		case errors.Is(err, ErrProductNotScheduled):
+			body["error"] = "Product is not served at this time"
+		case errors.Is(err, ErrNotEnoughStock):
+			body["error"] = "Not enough stock"
+		case errors.Is(err, ErrInvalidModifiers):
+			body["error"] = "Invalid modifiers"
+			body["details"] = err.Error()
+		case errors.Is(err, ErrComboNotFound):
+			body["error"] = "Combo not found"
+		case errors.Is(err, ErrComboUnavailable):
+			body["error"] = "Combo is not available"
+		case errors.Is(err, ErrInvalidComboChoices):
+			body["error"] = "Invalid combo choices"
+			body["details"] = err.Error()
+		default:
+			body["error"] = err.Error()
+		}
+		return http.StatusBadRequest, body
+	}
+	if errors.Is(err, ErrEmptyOrder) {
+		return http.StatusBadRequest, gin.H{"error": "Order has no items"}
+	}
+	switch {
+	case errors.Is(err, ErrSlotRequired):
+		return http.StatusBadRequest, gin.H{"error": "Pickup slot is required"}
+	case errors.Is(err, ErrSlotNotFound):
+		return http.StatusBadRequest, gin.H{"error": "Pickup slot not found"}
+	case errors.Is(err, ErrSlotUnavailable):
+		return http.StatusBadRequest, gin.H{"error": "Pickup slot is not available"}
+	case errors.Is(err, ErrSlotFull):
+		return http.StatusConflict, gin.H{"error": "Pickup slot is full"}
+	}
+	return http.StatusInternalServerError, gin.H{"error": "Failed to create order"}
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// TestCreateOrderChecksScheduleAtPickup places an order at breakfast for
+// pickup at lunch.
+func TestCreateOrderChecksScheduleAtPickup(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	today := time.Now()
+	placedAt := time.Date(today.Year(), today.Month(), today.Day(), 8, 30, 0, 0, time.Local)
+	timeNow = func() time.Time { return placedAt }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	user := createUser(t, db, "client", models.Client)
+	slot := models.PickupSlot{Start: "12:30", End: "12:40", IsActive: true}
+	if err := db.Create(&slot).Error; err != nil {
+		t.Fatal(err)
+	}
+	item := func(name string, period models.MealPeriod) models.Menu {
+		menuItem := models.Menu{
+			Name:        name,
+			Price:       decimal.NewFromInt(900),
+			Quantity:    10,
+			IsAvailable: true,
+			Schedules:   []models.MenuSchedule{{MealPeriod: period}},
+		}
+		if err := db.Create(&menuItem).Error; err != nil {
+			t.Fatal(err)
+		}
+		return menuItem
+	}
+	porridge := item("Porridge", models.Breakfast)
+	soup := item("Soup", models.Lunch)
+
+	order := func(itemID uint) error {
+		return db.Transaction(func(tx *gorm.DB) error {
+			_, err := CreateOrder(tx, user.ID, OrderInput{
+				Lines:  []OrderLine{{ItemID: itemID, Quantity: 1}},
+				SlotID: slot.ID,
+			})
+			return err
+		})
+	}
+	if err := order(soup.ID); err != nil {
+		t.Errorf("lunch item for lunch pickup: %v", err)
+	}
+	if err := order(porridge.ID); !errors.Is(err, ErrProductNotScheduled) {
+		t.Errorf("breakfast item for lunch pickup: err = %v, want ErrProductNotScheduled", err)
+	}
+}
+
+func TestCreateOrderUnitPriceIncludesModifiers(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	slot := lateSlot(t, db)
+	user := createUser(t, db, "client", models.Client)
+	item := models.Menu{Name: "Burger", Price: decimal.NewFromInt(1500), Quantity: 10, IsAvailable: true}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	group := models.ModifierGroup{
+		ItemID:    item.ID,
+		Name:      "Extras",
+		Kind:      models.Extra,
+		MaxSelect: 1,
+		Options:   []models.ModifierOption{{Name: "Cheese", PriceDelta: decimal.NewFromInt(200)}},
+	}
+	if err := db.Create(&group).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var order models.Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, err = CreateOrder(tx, user.ID, OrderInput{
+			Lines:  []OrderLine{{ItemID: item.ID, Quantity: 2, OptionIDs: []uint{group.Options[0].ID}}},
+			SlotID: slot.ID,
+		})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	detail := order.OrderDetails[0]
+	if !detail.UnitPrice.Equal(decimal.NewFromInt(1700)) {
+		t.Errorf("unit price = %s, want 1700", detail.UnitPrice)
+	}
+	if !detail.TotalCost.Equal(decimal.NewFromInt(3400)) {
+		t.Errorf("total cost = %s, want 3400", detail.TotalCost)
+	}
+}
@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckPickupCode(t *testing.T) {
+	order := models.Order{ID: 42, PickupNumber: 7, PickupDate: time.Date(2024, 5, 17, 0, 0, 0, 0, time.Local)}
+	code := PickupCode(order)
+
+	orderID, err := CheckPickupCode(code)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if orderID != order.ID {
+		t.Errorf("order id = %d, want %d", orderID, order.ID)
+	}
+	if _, err := CheckPickupCode(" " + code + "\n"); err != nil {
+		t.Errorf("code with surrounding spaces: %v", err)
+	}
+
+	i := strings.LastIndex(code, ".")
+	payload, signature := code[:i], code[i+1:]
+	flipped := []byte(signature)
+	if flipped[0] == 'A' {
+		flipped[0] = 'B'
+	} else {
+		flipped[0] = 'A'
+	}
+	invalid := map[string]string{
+		"empty":               "",
+		"no signature":        payload,
+		"truncated signature": code[:len(code)-2],
+		"truncated payload":   code[2:],
+		"tampered order":      "43" + code[2:],
+		"tampered number":     strings.Replace(payload, ".7", ".8", 1) + "." + signature,
+		"tampered signature":  payload + "." + string(flipped),
+		"other order's code":  payload + "." + pickupSignature("43.20240517.7"),
+	}
+	for name, code := range invalid {
+		if _, err := CheckPickupCode(code); !errors.Is(err, ErrInvalidPickupCode) {
+			t.Errorf("%s: err = %v, want ErrInvalidPickupCode", name, err)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+package utils
+
+import (
+	"final_project/internal/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// RecordPriceChange adds a price history entry for the menu item when its
+// price actually changed. changedBy is zero for changes not made by a user.
+func RecordPriceChange(tx *gorm.DB, itemID uint, oldPrice, newPrice decimal.Decimal, changedBy uint) error {
+	if oldPrice.Equal(newPrice) {
+		return nil
+	}
+	entry := models.MenuPriceHistory{
+		ItemID:   itemID,
+		OldPrice: oldPrice,
+		NewPrice: newPrice,
+	}
+	if changedBy != 0 {
+		entry.ChangedByID = &changedBy
+	}
+	return tx.Create(&entry).Error
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"math"
+
+	"gorm.io/gorm"
+)
+
+// VisibleRatings is the SQL of the average rating and review count per menu
+// item, leaving out hidden reviews.
+const VisibleRatings = "SELECT item_id, AVG(rating) AS average, COUNT(*) AS count FROM reviews WHERE hidden = false GROUP BY item_id"
+
+// HasReceivedItem reports whether the user got the menu item, on its own or
+// in a combo, in one of their completed orders.
+func HasReceivedItem(db *gorm.DB, userID, itemID uint) (bool, error) {
+	completed := db.Model(&models.Order{}).Select("id").Where("user_id = ? AND order_status = ?", userID, models.Completed)
+	var count int64
+	err := db.Model(&models.OrderDetail{}).Where("item_id = ? AND order_id IN (?)", itemID, completed).Count(&count).Error
+	if err != nil || count > 0 {
+		return count > 0, err
+	}
+	err = db.Model(&models.OrderComboChoice{}).
+		Joins("JOIN order_combos ON order_combos.id = order_combo_choices.order_combo_id").
+		Where("order_combo_choices.item_id = ? AND order_combos.order_id IN (?)", itemID, completed).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// RateMenuItems fills in the rating and review count of the items.
+func RateMenuItems(db *gorm.DB, items []models.Menu) error {
+	if len(items) == 0 {
+		return nil
+	}
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	var rows []struct {
+		ItemID  uint
+		Average float64
+		Count   int64
+	}
+	if err := db.Raw("SELECT * FROM ("+VisibleRatings+") AS ratings WHERE item_id IN ?", itemIDs).Scan(&rows).Error; err != nil {
+		return err
+	}
+	ratings := make(map[uint]int, len(rows))
+	for i, row := range rows {
+		ratings[row.ItemID] = i
+	}
+	for i := range items {
+		if j, ok := ratings[items[i].ID]; ok {
+			items[i].Rating = math.Round(rows[j].Average*10) / 10
+			items[i].RatingCount = rows[j].Count
+		}
+	}
+	return nil
+}
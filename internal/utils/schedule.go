@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrProductNotScheduled = errors.New("product is not served at this time")
+
+// ScheduledAt matches menu items served at t. Items without any schedule are
+// always served; otherwise one of their schedules must cover the weekday, the
+// date and the meal period of t.
+func ScheduledAt(t time.Time) clause.Expr {
+	periods := append(models.MealPeriodsAt(t), models.AllDay)
+	today := t.Format("2006-01-02")
+	return gorm.Expr(`NOT EXISTS (SELECT 1 FROM menu_schedules WHERE menu_schedules.item_id = menus.id)
+		OR EXISTS (SELECT 1 FROM menu_schedules WHERE menu_schedules.item_id = menus.id
+			AND (menu_schedules.weekdays = 0 OR menu_schedules.weekdays & ? <> 0)
+			AND (menu_schedules.start_date IS NULL OR menu_schedules.start_date <= ?)
+			AND (menu_schedules.end_date IS NULL OR menu_schedules.end_date >= ?)
+			AND menu_schedules.meal_period IN ?)`,
+		1<<uint(t.Weekday()), today, today, periods)
+}
+
+// IsScheduledAt reports whether the menu item is served at t.
+func IsScheduledAt(db *gorm.DB, itemID uint, t time.Time) (bool, error) {
+	var count int64
+	err := db.Model(&models.Menu{}).Where("menus.id = ?", itemID).Where(ScheduledAt(t)).Count(&count).Error
+	return count > 0, err
+}
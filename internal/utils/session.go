@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"final_project/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("Invalid refresh token")
+	ErrSessionRevoked      = errors.New("Session has been revoked")
+	ErrRefreshTokenReused  = errors.New("Refresh token reuse detected, session revoked")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+func newJTI() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func issueTokenPair(user models.User, session models.Session) (TokenPair, error) {
+	accessToken, err := GenerateToken(user.Username, string(user.Role), int(user.ID), session.ID)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refreshToken, err := GenerateRefreshToken(int(user.ID), session.ID, session.RefreshJTI)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// CreateSession starts a new session for the user and returns its first token pair.
+func CreateSession(db *gorm.DB, user models.User) (TokenPair, error) {
+	jti, err := newJTI()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	session := models.Session{
+		UserID:     user.ID,
+		RefreshJTI: jti,
+		ExpiresAt:  time.Now().Add(RefreshTokenTTL),
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return TokenPair{}, err
+	}
+	return issueTokenPair(user, session)
+}
+
+// RotateSession exchanges a refresh token for a new pair. Presenting a refresh
+// token that was already rotated revokes the whole session.
+func RotateSession(db *gorm.DB, refreshToken string) (TokenPair, error) {
+	claims, err := parseToken(refreshToken)
+	if err != nil {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	tokenType, _ := claims["type"].(string)
+	sessionID, okSID := claims["sid"].(float64)
+	userID, okID := claims["ID"].(float64)
+	jti, okJTI := claims["jti"].(string)
+	if tokenType != refreshTokenType || !okSID || !okID || !okJTI {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	var session models.Session
+	if err := db.Preload("User").First(&session, uint(sessionID)).Error; err != nil || session.UserID != uint(userID) {
+		return TokenPair{}, ErrInvalidRefreshToken
+	}
+	if !session.IsActive() || session.User.Disabled {
+		return TokenPair{}, ErrSessionRevoked
+	}
+
+	newJti, err := newJTI()
+	if err != nil {
+		return TokenPair{}, err
+	}
+	result := db.Model(&models.Session{}).
+		Where("id = ? AND refresh_jti = ? AND revoked_at IS NULL", session.ID, jti).
+		Update("refresh_jti", newJti)
+	if result.Error != nil {
+		return TokenPair{}, result.Error
+	}
+	if result.RowsAffected == 0 {
+		if err := RevokeSession(db, session.ID); err != nil {
+			return TokenPair{}, err
+		}
+		return TokenPair{}, ErrRefreshTokenReused
+	}
+
+	session.RefreshJTI = newJti
+	return issueTokenPair(session.User, session)
+}
+
+func RevokeSession(db *gorm.DB, sessionID uint) error {
+	return db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func RevokeUserSessions(db *gorm.DB, userID uint) error {
+	return db.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
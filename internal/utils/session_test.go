@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"testing"
+)
+
+func TestRotateSessionDetectsReuse(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+
+	first, err := CreateSession(db, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RotateSession(db, first.AccessToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("rotating with an access token: err = %v, want ErrInvalidRefreshToken", err)
+	}
+	if _, err := RotateSession(db, "not a token"); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("rotating with garbage: err = %v, want ErrInvalidRefreshToken", err)
+	}
+
+	second, err := RotateSession(db, first.RefreshToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatal("rotation returned the same refresh token")
+	}
+
+	// The first token was already used, so whoever holds it now may have
+	// stolen it and the whole session is revoked.
+	if _, err := RotateSession(db, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("reusing a refresh token: err = %v, want ErrRefreshTokenReused", err)
+	}
+	var session models.Session
+	if err := db.Where("user_id = ?", user.ID).First(&session).Error; err != nil {
+		t.Fatal(err)
+	}
+	if session.IsActive() {
+		t.Error("session is still active after reuse")
+	}
+	if _, err := RotateSession(db, second.RefreshToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Errorf("rotating after reuse: err = %v, want ErrSessionRevoked", err)
+	}
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSlotRequired    = errors.New("pickup slot is required")
+	ErrSlotNotFound    = errors.New("pickup slot not found")
+	ErrSlotUnavailable = errors.New("pickup slot is not available")
+	ErrSlotFull        = errors.New("pickup slot is full")
+)
+
+// SlotUsage is what is booked in a pickup slot on one day.
+type SlotUsage struct {
+	SlotID uint
+	Orders int
+	Items  int
+}
+
+// SlotUsages counts the orders and portions booked in each slot on the day.
+// Canceled orders free their place.
+func SlotUsages(db *gorm.DB, day time.Time) (map[uint]SlotUsage, error) {
+	from := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	var rows []SlotUsage
+	err := db.Raw(`
+		SELECT orders.pickup_slot_id AS slot_id, COUNT(*) AS orders,
+			COALESCE(SUM(
+				(SELECT COALESCE(SUM(quantity), 0) FROM order_details WHERE order_details.order_id = orders.id) +
+				(SELECT COALESCE(SUM(order_combos.quantity * (SELECT COUNT(*) FROM order_combo_choices WHERE order_combo_choices.order_combo_id = order_combos.id)), 0)
+					FROM order_combos WHERE order_combos.order_id = orders.id)
+			), 0) AS items
+		FROM orders
+		WHERE orders.pickup_slot_id IS NOT NULL AND orders.order_status <> ? AND orders.pickup_at >= ? AND orders.pickup_at < ?
+		GROUP BY orders.pickup_slot_id`,
+		models.Canceled, from, from.AddDate(0, 0, 1),
+	).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	usages := make(map[uint]SlotUsage, len(rows))
+	for _, row := range rows {
+		usages[row.SlotID] = row
+	}
+	return usages, nil
+}
+
+// LockSlot locks the pickup slot until tx ends, so concurrent orders cannot
+// overbook it, and checks that it can still be booked by an order placed at
+// the given time.
+func LockSlot(tx *gorm.DB, slotID uint, at time.Time) (models.PickupSlot, error) {
+	var slot models.PickupSlot
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&slot, slotID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return slot, ErrSlotNotFound
+		}
+		return slot, err
+	}
+	if !slot.IsActive || !slot.StartOn(at).After(at) {
+		return slot, ErrSlotUnavailable
+	}
+	return slot, nil
+}
+
+// CheckSlotCapacity checks that an order of items portions placed at the
+// given time still fits in the locked slot on that day.
+func CheckSlotCapacity(tx *gorm.DB, slot models.PickupSlot, items int, at time.Time) error {
+	usages, err := SlotUsages(tx, at)
+	if err != nil {
+		return err
+	}
+	usage := usages[slot.ID]
+	if slot.MaxOrders > 0 && usage.Orders+1 > slot.MaxOrders {
+		return ErrSlotFull
+	}
+	if slot.MaxItems > 0 && usage.Items+items > slot.MaxItems {
+		return ErrSlotFull
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+func TestReserveSlotCapacity(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+	item := models.Menu{Name: "Manty", Price: decimal.NewFromInt(1200), Quantity: 100, IsAvailable: true}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	order := func(slotID uint, quantity int) (models.Order, error) {
+		var created models.Order
+		err := db.Transaction(func(tx *gorm.DB) error {
+			var err error
+			created, err = CreateOrder(tx, user.ID, OrderInput{
+				Lines:  []OrderLine{{ItemID: item.ID, Quantity: quantity}},
+				SlotID: slotID,
+			})
+			return err
+		})
+		return created, err
+	}
+
+	slot := lateSlot(t, db)
+	if err := db.Model(&slot).Updates(map[string]interface{}{"max_orders": 2, "max_items": 5}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := order(slot.ID, 6); !errors.Is(err, ErrSlotFull) {
+		t.Fatalf("6 portions in a 5 portion slot: err = %v, want ErrSlotFull", err)
+	}
+	first, err := order(slot.ID, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.PickupAt == nil || !first.PickupAt.Equal(slot.StartOn(time.Now())) {
+		t.Errorf("pickup at = %v, want the slot start", first.PickupAt)
+	}
+	if _, err := order(slot.ID, 3); !errors.Is(err, ErrSlotFull) {
+		t.Fatalf("8 portions in a 5 portion slot: err = %v, want ErrSlotFull", err)
+	}
+	if _, err := order(slot.ID, 2); err != nil {
+		t.Fatalf("second order: %v", err)
+	}
+	if _, err := order(slot.ID, 1); !errors.Is(err, ErrSlotFull) {
+		t.Fatalf("third order in a 2 order slot: err = %v, want ErrSlotFull", err)
+	}
+
+	// A canceled order gives its place back.
+	first.OrderStatus = models.Canceled
+	if err := db.Save(&first).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := order(slot.ID, 3); err != nil {
+		t.Fatalf("order after a cancelation: %v", err)
+	}
+
+	var stock models.Menu
+	if err := db.First(&stock, item.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stock.Quantity != 100-3-2-3 {
+		t.Errorf("stock = %d, rejected orders must not take stock", stock.Quantity)
+	}
+}
+
+func TestLockSlotUnavailable(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	now := time.Now()
+
+	inactive := lateSlot(t, db)
+	if err := db.Model(&inactive).Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	started := models.PickupSlot{Start: "00:00", End: "00:01", IsActive: true}
+	if err := db.Create(&started).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name   string
+		slotID uint
+		want   error
+	}{
+		{"missing", inactive.ID + started.ID + 1, ErrSlotNotFound},
+		{"inactive", inactive.ID, ErrSlotUnavailable},
+		{"started", started.ID, ErrSlotUnavailable},
+	}
+	for _, c := range cases {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			_, err := LockSlot(tx, c.slotID, now)
+			return err
+		})
+		if !errors.Is(err, c.want) {
+			t.Errorf("%s: err = %v, want %v", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"gorm.io/gorm"
+	"sort"
+)
+
+var ErrNotEnoughStock = errors.New("not enough stock")
+
+// DecrementStock takes quantity portions of a menu item in a single
+// conditional UPDATE, so concurrent orders cannot sell more than is in stock.
+// An item that runs out is marked unavailable.
+func DecrementStock(tx *gorm.DB, itemID uint, quantity int) error {
+	result := tx.Model(&models.Menu{}).
+		Where("id = ? AND quantity >= ?", itemID, quantity).
+		Updates(map[string]interface{}{
+			"is_available": gorm.Expr("CASE WHEN quantity - ? <= 0 THEN FALSE ELSE is_available END", quantity),
+			"quantity":     gorm.Expr("quantity - ?", quantity),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotEnoughStock
+	}
+	return nil
+}
+
+// IncrementStock returns quantity portions of a menu item and makes it
+// available again if it had run out.
+func IncrementStock(tx *gorm.DB, itemID uint, quantity int) error {
+	return tx.Model(&models.Menu{}).Where("id = ?", itemID).Updates(map[string]interface{}{
+		"is_available": gorm.Expr("CASE WHEN quantity <= 0 THEN TRUE ELSE is_available END"),
+		"quantity":     gorm.Expr("quantity + ?", quantity),
+	}).Error
+}
+
+// RestockOrder returns the quantities of every line of the order, including
+// the items chosen in its combos, to the menu. Like takeStock it updates the
+// menu items in id order.
+func RestockOrder(tx *gorm.DB, orderID uint) error {
+	var details []models.OrderDetail
+	if err := tx.Where("order_id = ?", orderID).Find(&details).Error; err != nil {
+		return err
+	}
+	takes := make([]stockTake, 0, len(details))
+	for _, detail := range details {
+		takes = append(takes, stockTake{ItemID: detail.ItemID, Quantity: detail.Quantity})
+	}
+
+	var combos []models.OrderCombo
+	if err := tx.Preload("Choices").Where("order_id = ?", orderID).Find(&combos).Error; err != nil {
+		return err
+	}
+	for _, combo := range combos {
+		for _, choice := range combo.Choices {
+			takes = append(takes, stockTake{ItemID: choice.ItemID, Quantity: combo.Quantity})
+		}
+	}
+
+	sort.SliceStable(takes, func(i, j int) bool { return takes[i].ItemID < takes[j].ItemID })
+	for _, take := range takes {
+		if err := IncrementStock(tx, take.ItemID, take.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"errors"
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// lateSlot returns a slot that still takes orders for most of the day.
+func lateSlot(t *testing.T, db *gorm.DB) models.PickupSlot {
+	t.Helper()
+	if now := time.Now(); now.Hour() == 23 && now.Minute() >= 50 {
+		t.Skip("no pickup slot is left today")
+	}
+	slot := models.PickupSlot{Start: "23:55", End: "23:59", IsActive: true}
+	if err := db.Create(&slot).Error; err != nil {
+		t.Fatal(err)
+	}
+	return slot
+}
+
+func createUser(t *testing.T, db *gorm.DB, name string, role models.Role) models.User {
+	t.Helper()
+	user := models.User{Username: name, Email: name + "@example.com", Role: role}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestCreateOrderConcurrentStock(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	const buyers, stock = 20, 7
+
+	slot := lateSlot(t, db)
+	item := models.Menu{Name: "Samsa", Price: decimal.NewFromInt(600), Quantity: stock, IsAvailable: true}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+	users := make([]models.User, buyers)
+	for i := range users {
+		users[i] = createUser(t, db, fmt.Sprintf("buyer%d", i), models.Client)
+	}
+
+	errs := make([]error, buyers)
+	var wg sync.WaitGroup
+	for i := range users {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = db.Transaction(func(tx *gorm.DB) error {
+				_, err := CreateOrder(tx, users[i].ID, OrderInput{
+					Lines:  []OrderLine{{ItemID: item.ID, Quantity: 1}},
+					SlotID: slot.ID,
+				})
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	succeeded, outOfStock := 0, 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, ErrNotEnoughStock), errors.Is(err, ErrProductUnavailable):
+			outOfStock++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+	if succeeded != stock || outOfStock != buyers-stock {
+		t.Errorf("succeeded = %d, out of stock = %d, want %d and %d", succeeded, outOfStock, stock, buyers-stock)
+	}
+
+	if err := db.First(&item, item.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if item.Quantity != 0 {
+		t.Errorf("stock = %d, want 0", item.Quantity)
+	}
+	if item.IsAvailable {
+		t.Error("item is still available after selling out")
+	}
+	var orders int64
+	db.Model(&models.Order{}).Count(&orders)
+	if orders != stock {
+		t.Errorf("orders = %d, want %d", orders, stock)
+	}
+}
+
+// TestCreateOrderOppositeItemOrder places orders listing the same items in
+// opposite orders at the same time. They use different slots so the slot
+// lock does not already serialize them.
+func TestCreateOrderOppositeItemOrder(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	const buyers = 20
+
+	slots := []models.PickupSlot{lateSlot(t, db), {Start: "23:51", End: "23:55", IsActive: true}}
+	if err := db.Create(&slots[1]).Error; err != nil {
+		t.Fatal(err)
+	}
+	items := []models.Menu{
+		{Name: "Tea", Price: decimal.NewFromInt(200), Quantity: 100, IsAvailable: true},
+		{Name: "Baursak", Price: decimal.NewFromInt(300), Quantity: 100, IsAvailable: true},
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	user := createUser(t, db, "buyer", models.Client)
+
+	errs := make([]error, buyers)
+	var wg sync.WaitGroup
+	for i := 0; i < buyers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lines := []OrderLine{{ItemID: items[0].ID, Quantity: 1}, {ItemID: items[1].ID, Quantity: 1}}
+			if i%2 == 1 {
+				lines[0], lines[1] = lines[1], lines[0]
+			}
+			errs[i] = db.Transaction(func(tx *gorm.DB) error {
+				_, err := CreateOrder(tx, user.ID, OrderInput{Lines: lines, SlotID: slots[i%2].ID})
+				return err
+			})
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("order %d: %v", i, err)
+		}
+	}
+	for _, item := range items {
+		if err := db.First(&item, item.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+		if item.Quantity != 100-buyers {
+			t.Errorf("%s stock = %d, want %d", item.Name, item.Quantity, 100-buyers)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps idle streams from being closed by proxies.
+const heartbeatInterval = 25 * time.Second
+
+// StreamTicketTTL is how long a stream ticket can be used.
+const StreamTicketTTL = 30 * time.Second
+
+// streamTickets holds the tickets that have not been used yet. Like the
+// event hub, they live in the memory of this server.
+var streamTickets = struct {
+	sync.Mutex
+	byTicket map[string]streamTicket
+}{byTicket: map[string]streamTicket{}}
+
+type streamTicket struct {
+	authorization string
+	expiresAt     time.Time
+}
+
+// IssueStreamTicket returns a ticket that opens one event stream within
+// StreamTicketTTL. authorization is the Authorization header of the request
+// asking for it; the stream checks it again when it connects.
+func IssueStreamTicket(authorization string) (string, error) {
+	ticket, err := newJTI()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	streamTickets.Lock()
+	defer streamTickets.Unlock()
+	for key, unused := range streamTickets.byTicket {
+		if now.After(unused.expiresAt) {
+			delete(streamTickets.byTicket, key)
+		}
+	}
+	streamTickets.byTicket[ticket] = streamTicket{authorization: authorization, expiresAt: now.Add(StreamTicketTTL)}
+	return ticket, nil
+}
+
+// redeemStreamTicket uses up the ticket and returns the Authorization header
+// it was issued for.
+func redeemStreamTicket(ticket string) (string, bool) {
+	streamTickets.Lock()
+	defer streamTickets.Unlock()
+	unused, ok := streamTickets.byTicket[ticket]
+	delete(streamTickets.byTicket, ticket)
+	if !ok || time.Now().After(unused.expiresAt) {
+		return "", false
+	}
+	return unused.authorization, true
+}
+
+// StreamAuthMiddleware is AuthMiddleware for event streams. Browsers cannot
+// set headers on an EventSource, so such clients get a ticket from
+// POST /stream/ticket and pass it in the ticket query parameter instead. A
+// ticket works once and only briefly, so a stream URL that ends up in a log
+// or the browser history cannot be used to listen in.
+func StreamAuthMiddleware() gin.HandlerFunc {
+	auth := AuthMiddleware()
+	return func(c *gin.Context) {
+		if ticket := c.Query("ticket"); ticket != "" && c.GetHeader("Authorization") == "" {
+			authorization, ok := redeemStreamTicket(ticket)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid stream ticket"})
+				return
+			}
+			c.Request.Header.Set("Authorization", authorization)
+		}
+		auth(c)
+	}
+}
+
+// StreamEvents sends the events as Server-Sent Events until the client goes
+// away or its access token expires, with a ping event every 25 seconds.
+// render turns an event into the data that is sent.
+func StreamEvents(c *gin.Context, events <-chan Event, render func(Event) interface{}) {
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent("ready", gin.H{"at": time.Now()})
+	c.Writer.Flush()
+
+	// The token is only checked when connecting, so the stream ends with it
+	// and the client reconnects with a fresh one.
+	expired := make(<-chan time.Time)
+	if expiresAt, ok := c.Get("expires_at"); ok {
+		timer := time.NewTimer(time.Until(expiresAt.(time.Time)))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-expired:
+			c.SSEvent("expired", gin.H{"at": time.Now()})
+			return false
+		case <-heartbeat.C:
+			c.SSEvent("ping", gin.H{"at": time.Now()})
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, render(event))
+			return true
+		}
+	})
+}
@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"final_project/internal/testdb"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestStreamTicket(t *testing.T) {
+	db := testdb.Open(t, "utils")
+	user := createUser(t, db, "client", models.Client)
+	tokens, err := CreateSession(db, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/stream", StreamAuthMiddleware(), func(c *gin.Context) {
+		userID, _ := c.Get("ID")
+		if userID != user.ID {
+			t.Errorf("stream opened as %v, want %d", userID, user.ID)
+		}
+		c.Status(http.StatusOK)
+	})
+	open := func(query string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream?"+query, nil))
+		return w.Code
+	}
+
+	if code := open("access_token=" + tokens.AccessToken); code != http.StatusUnauthorized {
+		t.Errorf("access token in the URL: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	ticket, err := IssueStreamTicket("Bearer " + tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code := open("ticket=" + ticket); code != http.StatusOK {
+		t.Errorf("ticket: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := open("ticket=" + ticket); code != http.StatusUnauthorized {
+		t.Errorf("used ticket: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	expired, err := IssueStreamTicket("Bearer " + tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamTickets.Lock()
+	entry := streamTickets.byTicket[expired]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	streamTickets.byTicket[expired] = entry
+	streamTickets.Unlock()
+	if code := open("ticket=" + expired); code != http.StatusUnauthorized {
+		t.Errorf("expired ticket: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+
+	// The session is checked again when the stream connects.
+	revoked, err := IssueStreamTicket("Bearer " + tokens.AccessToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Model(&models.Session{}).Where("user_id = ?", user.ID).Update("revoked_at", time.Now()).Error; err != nil {
+		t.Fatal(err)
+	}
+	if code := open("ticket=" + revoked); code != http.StatusUnauthorized {
+		t.Errorf("ticket of a revoked session: status = %d, want %d", code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"final_project/internal/models"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// FindTags loads the tags with the given names. If kind is not empty every
+// tag must be of that kind. Unknown names are reported in the error.
+func FindTags(db *gorm.DB, names []string, kind models.TagKind) ([]models.Tag, error) {
+	tags := []models.Tag{}
+	if len(names) == 0 {
+		return tags, nil
+	}
+
+	normalized := make([]string, 0, len(names))
+	for _, name := range names {
+		normalized = append(normalized, strings.ToLower(strings.TrimSpace(name)))
+	}
+
+	query := db.Where("name IN ?", normalized)
+	if kind != "" {
+		query = query.Where("kind = ?", kind)
+	}
+	if err := query.Find(&tags).Error; err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		found[tag.Name] = true
+	}
+	for _, name := range normalized {
+		if !found[name] {
+			return nil, fmt.Errorf("Unknown tag: %s", name)
+		}
+	}
+	return tags, nil
+}
+
+// AllergenConflicts returns, for each of the given menu items, the names of the
+// allergens it contains that the user has declared.
+func AllergenConflicts(db *gorm.DB, userID uint, itemIDs []uint) (map[uint][]string, error) {
+	conflicts := map[uint][]string{}
+	if len(itemIDs) == 0 {
+		return conflicts, nil
+	}
+
+	var rows []struct {
+		MenuID uint
+		Name   string
+	}
+	err := db.Table("menu_tags").
+		Select("menu_tags.menu_id, tags.name").
+		Joins("JOIN user_allergens ON user_allergens.tag_id = menu_tags.tag_id").
+		Joins("JOIN tags ON tags.id = menu_tags.tag_id").
+		Where("user_allergens.user_id = ? AND menu_tags.menu_id IN ?", userID, itemIDs).
+		Order("tags.name").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		conflicts[row.MenuID] = append(conflicts[row.MenuID], row.Name)
+	}
+	return conflicts, nil
+}
+
+// AllergenWarnings turns AllergenConflicts into human-readable messages.
+func AllergenWarnings(db *gorm.DB, userID uint, items []models.Menu) ([]string, error) {
+	itemIDs := make([]uint, 0, len(items))
+	for _, item := range items {
+		itemIDs = append(itemIDs, item.ID)
+	}
+	conflicts, err := AllergenConflicts(db, userID, itemIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings := []string{}
+	warned := map[uint]bool{}
+	for _, item := range items {
+		if allergens, ok := conflicts[item.ID]; ok && !warned[item.ID] {
+			warnings = append(warnings, fmt.Sprintf("%s contains %s", item.Name, strings.Join(allergens, ", ")))
+			warned[item.ID] = true
+		}
+	}
+	return warnings, nil
+}
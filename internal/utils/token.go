@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"final_project/initializers"
 	"final_project/internal/models"
 	"fmt"
 	"net/http"
@@ -15,16 +16,54 @@ import (
 
 var jwtKey = []byte(os.Getenv("my_secret"))
 
-func GenerateToken(username string, role string, ID int) (string, error) {
+const (
+	AccessTokenTTL  = 15 * time.Minute
+	RefreshTokenTTL = 7 * 24 * time.Hour
+
+	accessTokenType  = "access"
+	refreshTokenType = "refresh"
+)
+
+func GenerateToken(username string, role string, ID int, sessionID uint) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"username": username,
 		"role":     role,
 		"ID":       ID,
-		"exp":      time.Now().Add(time.Hour * 1).Unix(),
+		"sid":      sessionID,
+		"type":     accessTokenType,
+		"exp":      time.Now().Add(AccessTokenTTL).Unix(),
+	})
+	return token.SignedString(jwtKey)
+}
+
+func GenerateRefreshToken(ID int, sessionID uint, jti string) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"ID":   ID,
+		"sid":  sessionID,
+		"jti":  jti,
+		"type": refreshTokenType,
+		"exp":  time.Now().Add(RefreshTokenTTL).Unix(),
 	})
 	return token.SignedString(jwtKey)
 }
 
+func parseToken(tokenString string) (jwt.MapClaims, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		return jwtKey, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, fmt.Errorf("Invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, fmt.Errorf("Invalid token claims")
+	}
+	return claims, nil
+}
+
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tokenString := c.GetHeader("Authorization")
@@ -35,22 +74,15 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 
 		tokenString = strings.Replace(tokenString, "Bearer ", "", 1)
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-			}
-			return jwtKey, nil
-		})
-
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		claims, err := parseToken(tokenString)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+		if tokenType, _ := claims["type"].(string); tokenType != accessTokenType {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token type"})
 			c.Abort()
 			return
 		}
@@ -69,8 +101,35 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		sessionID, ok := claims["sid"].(float64)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session not found in token"})
+			c.Abort()
+			return
+		}
+
+		var session models.Session
+		if err := initializers.DB.Preload("User").First(&session, uint(sessionID)).Error; err != nil || !session.IsActive() || session.UserID != uint(userID) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			c.Abort()
+			return
+		}
+
+		if session.User.Disabled {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+			c.Abort()
+			return
+		}
+
+		// The role may have changed since the token was issued.
+		role = string(session.User.Role)
+
 		c.Set("role", role)
 		c.Set("ID", uint(userID))
+		c.Set("sid", session.ID)
+		if exp, ok := claims["exp"].(float64); ok {
+			c.Set("expires_at", time.Unix(int64(exp), 0))
+		}
 		c.Next()
 	}
 }
@@ -86,6 +145,8 @@ func SignupUser(db *gorm.DB, newUser models.User) error {
 		return fmt.Errorf("Failed to hash password")
 	}
 	newUser.Password = hashedPassword
+	newUser.Role = models.Client
+	newUser.Disabled = false
 	if err := db.Create(&newUser).Error; err != nil {
 		return fmt.Errorf("Failed to create user")
 	}